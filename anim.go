@@ -0,0 +1,271 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Easing curves map the fraction of a tween's time gone, from 0 to 1, to the fraction of the way moved
+type Ease func(t float64) float64
+
+func easeLinear(t float64) float64 {
+	return t
+}
+
+func easeInOutCubic(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	return 1 - math.Pow(-2*t+2, 3)/2
+}
+
+func easeOutQuad(t float64) float64 {
+	return 1 - (1-t)*(1-t)
+}
+
+// Overshoots a little and settles back, like a molecule snapping shut on its substrate
+func easeOutBack(t float64) float64 {
+	const c1 = 1.70158
+	const c3 = c1 + 1
+	return 1 + c3*math.Pow(t-1, 3) + c1*math.Pow(t-1, 2)
+}
+
+// Something that plays out over several steps. update advances it one fixed step and
+// reports whether it has finished.
+type Animation interface {
+	update() bool
+}
+
+// Moves a value from wherever it is when the tween starts to a target over a duration.
+// The start is read on the first step, so tweens in a sequence carry on from each other.
+type Tween struct {
+	duration float64
+	elapsed  float64
+	ease     Ease
+	start    func()
+	set      func(progress float64)
+	done     func()
+	started  bool
+}
+
+func (tw *Tween) update() bool {
+	if !tw.started {
+		tw.started = true
+		if tw.start != nil {
+			tw.start()
+		}
+	}
+	tw.elapsed += fixedStep
+	progress := 1.0
+	if tw.elapsed < tw.duration {
+		progress = tw.elapsed / tw.duration
+	}
+	tw.set(tw.ease(progress))
+	if progress < 1 {
+		return false
+	}
+	if tw.done != nil {
+		tw.done()
+	}
+	return true
+}
+
+// Calls fn once the tween has finished
+func (tw *Tween) then(fn func()) *Tween {
+	tw.done = fn
+	return tw
+}
+
+func tweenFloat(value *float64, to, duration float64, ease Ease) *Tween {
+	var from float64
+	return &Tween{
+		duration: duration, ease: ease,
+		start: func() { from = *value },
+		set:   func(p float64) { *value = from + (to-from)*p },
+	}
+}
+
+func tweenVector(pos *Vector, to Vector, duration float64, ease Ease) *Tween {
+	var from Vector
+	return &Tween{
+		duration: duration, ease: ease,
+		start: func() { from = *pos },
+		set: func(p float64) {
+			*pos = newVector(from.x+(to.x-from.x)*p, from.y+(to.y-from.y)*p)
+		},
+	}
+}
+
+// Waits a while, e.g. between the steps of a sequence
+func pause(duration float64) *Tween {
+	return &Tween{duration: duration, ease: easeLinear, set: func(float64) {}}
+}
+
+// Calls fn as a step of a sequence, taking no time
+type Call func()
+
+func (c Call) update() bool {
+	c()
+	return true
+}
+
+// Plays animations one after another
+type Sequence struct {
+	steps []Animation
+}
+
+func sequence(steps ...Animation) *Sequence {
+	return &Sequence{steps: steps}
+}
+
+func (s *Sequence) update() bool {
+	for len(s.steps) > 0 {
+		if !s.steps[0].update() {
+			return false
+		}
+		s.steps = s.steps[1:]
+	}
+	return true
+}
+
+// Animations playing in the current scene. The state machine steps them after the
+// scene and drops them all when the scene changes.
+type Tweens struct {
+	active     []Animation
+	generation int // Counts clears, so a callback that changes scene stops the step it is in
+}
+
+var tweens = &Tweens{}
+
+func (t *Tweens) add(a Animation) {
+	t.active = append(t.active, a)
+}
+
+func (t *Tweens) clear() {
+	t.active = nil
+	t.generation++
+}
+
+func (t *Tweens) update() {
+	generation := t.generation
+	current := t.active
+	// Animations added by callbacks go in after the ones still playing
+	t.active = nil
+	var playing []Animation
+	for _, a := range current {
+		finished := a.update()
+		if t.generation != generation {
+			return
+		}
+		if !finished {
+			playing = append(playing, a)
+		}
+	}
+	t.active = append(playing, t.active...)
+}
+
+// A run of frames played at a fixed rate, usually cut from one sprite sheet
+type Clip struct {
+	frames    []*ebiten.Image
+	frameTime float64 // Seconds each frame is shown
+	loop      bool
+}
+
+// Cuts count frames of one size from a sheet, left to right and then down, starting at frame first
+func sheetClip(sheet *ebiten.Image, frameW, frameH, first, count int, fps float64, loop bool) *Clip {
+	columns := sheet.Bounds().Dx() / frameW
+	origin := sheet.Bounds().Min
+	clip := &Clip{frameTime: 1 / fps, loop: loop}
+	for x := first; x < first+count; x++ {
+		corner := origin.Add(image.Pt(x%columns*frameW, x/columns*frameH))
+		clip.frames = append(clip.frames, sheet.SubImage(image.Rectangle{corner, corner.Add(image.Pt(frameW, frameH))}).(*ebiten.Image))
+	}
+	return clip
+}
+
+// Plays one named clip at a time and gives the frame to draw
+type Animator struct {
+	clips   map[string]*Clip
+	current *Clip
+	elapsed float64
+	onEnd   func() // Called when a clip that does not loop reaches its last frame
+}
+
+func newAnimator(clips map[string]*Clip) *Animator {
+	return &Animator{clips: clips}
+}
+
+// Starts a clip from its first frame
+func (a *Animator) play(name string) {
+	a.current, a.elapsed = a.clips[name], 0
+}
+
+// Advances the clip one step and returns its frame, or nil if nothing is playing
+func (a *Animator) update() *ebiten.Image {
+	if a.current == nil {
+		return nil
+	}
+	a.elapsed += fixedStep
+	frame := int(a.elapsed / a.current.frameTime)
+	if frame >= len(a.current.frames) {
+		if a.current.loop {
+			frame %= len(a.current.frames)
+		} else {
+			last := a.current.frames[len(a.current.frames)-1]
+			a.current = nil
+			if a.onEnd != nil {
+				a.onEnd()
+			}
+			return last
+		}
+	}
+	return a.current.frames[frame]
+}
+
+// Frames in a conformational change and how fast they play
+const (
+	conformationFrames = 8
+	conformationFPS    = 24
+)
+
+// Renders the frames of a molecule changing shape from one image to another onto a
+// sheet, fading the second in over the first
+func conformationSheet(from, to *ebiten.Image) *ebiten.Image {
+	w, h := from.Bounds().Dx(), from.Bounds().Dy()
+	sheet := ebiten.NewImage(w*conformationFrames, h)
+	for x := 0; x < conformationFrames; x++ {
+		fade := float32(x+1) / conformationFrames
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(float64(w*x), 0)
+		op.ColorScale.ScaleAlpha(1 - fade)
+		sheet.DrawImage(from, op)
+		op.ColorScale.Reset()
+		op.ColorScale.ScaleAlpha(fade)
+		sheet.DrawImage(to, op)
+	}
+	return sheet
+}
+
+// Animates a molecule switching between its inactive and active images, with clips
+// "activate" and "rest". The sheets are only rendered the first time it changes shape.
+type Conformation struct {
+	*Animator
+	inactive, active *ebiten.Image
+}
+
+func newConformation(inactive, active *ebiten.Image) *Conformation {
+	return &Conformation{Animator: newAnimator(nil), inactive: inactive, active: active}
+}
+
+func (c *Conformation) play(name string) {
+	if c.clips == nil {
+		w, h := c.inactive.Bounds().Dx(), c.inactive.Bounds().Dy()
+		c.clips = map[string]*Clip{
+			"activate": sheetClip(conformationSheet(c.inactive, c.active), w, h, 0, conformationFrames, conformationFPS, false),
+			"rest":     sheetClip(conformationSheet(c.active, c.inactive), w, h, 0, conformationFrames, conformationFPS, false),
+		}
+	}
+	c.Animator.play(name)
+}
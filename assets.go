@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Game files are built into the executable so it runs from anywhere
+//
+//go:embed Assets/Images Assets/Fonts/*.ttf Assets/Music
+var embeddedAssets embed.FS
+
+// Loads images, fonts and music by their path inside Assets, e.g. "Images/DNA.png".
+// Each file is decoded once and kept. A file in the override directory replaces the
+// embedded one with the same path, so art can be swapped without rebuilding.
+// Files can be decoded ahead of time by worker goroutines with load.
+type AssetManager struct {
+	override string
+	mutex    sync.Mutex
+	images   map[string]*ebiten.Image
+	fonts    map[string]*opentype.Font
+	sounds   map[string][]byte
+	failed   map[string]error
+	faces    map[fontFace]font.Face
+	atlas    Atlas
+	pending  map[string]chan struct{} // Files being decoded; the channel closes when they are done
+	jobs     chan assetJob
+	workers  sync.Once
+}
+
+type fontFace struct {
+	name string
+	size int
+}
+
+type assetJob struct {
+	name  string
+	batch *AssetBatch
+}
+
+// A group of files being decoded in the background, usually everything one scene needs
+type AssetBatch struct {
+	total int
+	done  atomic.Int32
+	wg    sync.WaitGroup
+}
+
+var assets = newAssetManager("")
+
+func newAssetManager(override string) *AssetManager {
+	return &AssetManager{
+		override: override,
+		images:   map[string]*ebiten.Image{},
+		fonts:    map[string]*opentype.Font{},
+		sounds:   map[string][]byte{},
+		failed:   map[string]error{},
+		faces:    map[fontFace]font.Face{},
+		pending:  map[string]chan struct{}{},
+	}
+}
+
+// Reads a file's raw bytes, preferring the override directory
+func (a *AssetManager) read(name string) ([]byte, error) {
+	if a.override != "" {
+		data, err := os.ReadFile(filepath.Join(a.override, filepath.FromSlash(name)))
+		if err == nil {
+			return data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return fs.ReadFile(embeddedAssets, path.Join("Assets", name))
+}
+
+// Queues files to be decoded by the worker goroutines and returns at once
+func (a *AssetManager) load(specs []AssetSpec) *AssetBatch {
+	a.workers.Do(func() {
+		a.jobs = make(chan assetJob)
+		for x := 0; x < runtime.NumCPU(); x++ {
+			go a.work()
+		}
+	})
+	batch := &AssetBatch{total: len(specs)}
+	batch.wg.Add(len(specs))
+	go func() {
+		for _, spec := range specs {
+			a.jobs <- assetJob{spec.name, batch}
+		}
+	}()
+	return batch
+}
+
+func (a *AssetManager) work() {
+	for job := range a.jobs {
+		a.fetch(job.name)
+		job.batch.done.Add(1)
+		job.batch.wg.Done()
+	}
+}
+
+// Whether every file is already decoded, so a scene using them can be built without waiting
+func (a *AssetManager) cached(specs []AssetSpec) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, spec := range specs {
+		if !a.decoded(spec.name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Must be called with the mutex held
+func (a *AssetManager) decoded(name string) bool {
+	_, img := a.images[name]
+	_, tt := a.fonts[name]
+	_, sound := a.sounds[name]
+	_, err := a.failed[name]
+	return img || tt || sound || err
+}
+
+// Makes sure a file is decoded: returns if it already is, waits if a worker is
+// decoding it, or else decodes it on this goroutine
+func (a *AssetManager) fetch(name string) {
+	a.mutex.Lock()
+	if a.decoded(name) {
+		a.mutex.Unlock()
+		return
+	}
+	if done, ok := a.pending[name]; ok {
+		a.mutex.Unlock()
+		<-done
+		return
+	}
+	done := make(chan struct{})
+	a.pending[name] = done
+	a.mutex.Unlock()
+
+	img, tt, sound, err := a.decode(name)
+
+	a.mutex.Lock()
+	switch {
+	case err != nil:
+		a.failed[name] = err
+	case img != nil:
+		a.images[name] = a.atlas.add(img)
+	case tt != nil:
+		a.fonts[name] = tt
+	default:
+		a.sounds[name] = sound
+	}
+	delete(a.pending, name)
+	a.mutex.Unlock()
+	close(done)
+}
+
+// Decodes a file by the folder it is in. Music stays encoded, since it is streamed when played.
+func (a *AssetManager) decode(name string) (*ebiten.Image, *opentype.Font, []byte, error) {
+	data, err := a.read(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	switch path.Dir(name) {
+	case "Images":
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return ebiten.NewImageFromImage(img), nil, nil, nil
+	case "Fonts":
+		tt, err := opentype.Parse(data)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return nil, tt, nil, nil
+	}
+	return nil, nil, data, nil
+}
+
+// Returns an image from Assets/Images; small ones are a region of an atlas page.
+// A missing or broken image is logged and replaced with a magenta square so the scene still loads.
+func (a *AssetManager) image(name string) *ebiten.Image {
+	name = "Images/" + name
+	a.fetch(name)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if img, ok := a.images[name]; ok {
+		return img
+	}
+	log.Println("asset:", a.failed[name])
+	img := ebiten.NewImage(64, 64)
+	img.Fill(color.RGBA{255, 0, 255, 255})
+	a.images[name] = img
+	return img
+}
+
+// Returns a face of a font from Assets/Fonts at a point size; the font is parsed once for all sizes
+func (a *AssetManager) font(name string, size int) (font.Face, error) {
+	a.fetch("Fonts/" + name)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	key := fontFace{name, size}
+	if face, ok := a.faces[key]; ok {
+		return face, nil
+	}
+	tt, ok := a.fonts["Fonts/"+name]
+	if !ok {
+		return nil, a.failed["Fonts/"+name]
+	}
+	face, err := opentype.NewFace(tt, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     72,
+		Hinting: font.HintingVertical,
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.faces[key] = face
+	return face, nil
+}
+
+// Returns a music file from Assets/Music, still encoded
+func (a *AssetManager) music(name string) ([]byte, error) {
+	a.fetch("Music/" + name)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if sound, ok := a.sounds["Music/"+name]; ok {
+		return sound, nil
+	}
+	return nil, a.failed["Music/"+name]
+}
+
+// Fraction of the batch decoded so far, from 0 to 1
+func (b *AssetBatch) progress() float64 {
+	if b.total == 0 {
+		return 1
+	}
+	return float64(b.done.Load()) / float64(b.total)
+}
+
+func (b *AssetBatch) finished() bool {
+	return int(b.done.Load()) == b.total
+}
+
+// Blocks until the whole batch is decoded
+func (b *AssetBatch) wait() {
+	b.wg.Wait()
+}
+
+// A file a scene needs. Images list the pixel size they were drawn at, since
+// sprite rectangles and scales are tuned to it; other files leave it at zero.
+type AssetSpec struct {
+	name          string
+	width, height int
+}
+
+// Background of the scenes inside the nucleus. There is no art of the nucleus's inside
+// yet, so they show the cytoplasm with the nucleus in view.
+const nucleusBackground = "CytoBg2.png"
+
+// Every asset each scene loads. "Shared" holds what is loaded at startup or by
+// more than one kind of scene (buttons, nucleobases, strands, fonts, music).
+var assetManifest = map[string][]AssetSpec{
+	"Shared": {
+		{"Fonts/CourierPrime-Regular.ttf", 0, 0}, {"Fonts/BlackOpsOne-Regular.ttf", 0, 0},
+		{"Music/Signaling_of_the_Cell_MenuScreen.mp3", 0, 0},
+		{"Images/infoButton.png", 165, 165}, {"Images/infoPage.png", 1250, 750},
+		{"Images/menuButton.png", 276, 184}, {"Images/codonButton.png", 480, 265},
+		{"Images/volButtonOn.png", 142, 149}, {"Images/volButtonOff.png", 142, 149},
+		{"Images/adenine.png", 135, 315}, {"Images/thymine.png", 135, 315},
+		{"Images/guanine.png", 135, 315}, {"Images/cytosine.png", 135, 315},
+		{"Images/uracil.png", 135, 315}, {"Images/empty.png", 129, 141},
+		{"Images/aminoAcid.png", 201, 201}, {"Images/DNA.png", 2500, 525},
+		{"Images/RNA0.png", 1788, 914}, {"Images/RNA1.png", 1788, 914},
+		{"Images/RNA2.png", 1788, 914}, {"Images/RNA3.png", 1788, 914},
+		{"Images/RNA4.png", 1788, 914}, {"Images/RNA5.png", 1788, 914},
+	},
+	"Main Menu": {
+		{"Images/MenuBg.png", 2500, 1500}, {"Images/StartBg.png", 2500, 1500},
+		{"Images/fixed-Start.png", 2500, 1500}, {"Images/parallax-Start2.png", 2500, 1500},
+		{"Images/parallax-Start3.png", 2500, 1500}, {"Images/parallax-Start4.png", 2500, 1500},
+		{"Images/parallax-Start5.png", 1458, 1623}, {"Images/PlayButton.png", 313, 215},
+		{"Images/aboutButton.png", 336, 239}, {"Images/levSelButton.png", 394, 242},
+	},
+	"About": {
+		{"Images/AboutBg.png", 2500, 1500},
+	},
+	"Level Selection": {
+		{"Images/levSelBg.png", 2500, 1500},
+		{"Images/levToPlasmaBtn.png", 308, 179}, {"Images/levToCyto1Btn.png", 308, 179},
+		{"Images/levToNucleusBtn.png", 308, 179}, {"Images/levToCyto2Btn.png", 308, 179},
+	},
+	"Signal Reception": {
+		{"Images/PlasmaBg.png", 2500, 1500}, {"Images/ParallaxPlasma.png", 2600, 1560},
+		{"Images/plasmaMembrane.png", 2650, 1426},
+		{"Images/inact_TK1.png", 330, 354}, {"Images/act_TK1.png", 330, 354},
+	},
+	"Signal Transduction": {
+		{"Images/CytoBg1.png", 2500, 1500}, {"Images/ParallaxCyto1.png", 2500, 1500},
+		{"Images/ParallaxCyto1.5.png", 2500, 1500},
+		{"Images/inact_TK1.png", 330, 354}, {"Images/act_TK1.png", 330, 354},
+		{"Images/inact_TK2.png", 378, 394}, {"Images/act_TK2.png", 378, 394},
+		{"Images/inact_TFA.png", 414, 304}, {"Images/act_TFA.png", 414, 304},
+	},
+	"Transcription": {
+		{"Images/" + nucleusBackground, 2500, 1500}, {"Images/rnaPolym.png", 651, 645},
+		{"Images/inact_TFA.png", 414, 304}, {"Images/act_TFA.png", 414, 304},
+	},
+	"Translation": {
+		{"Images/CytoBg2.png", 2500, 1500}, {"Images/ParallaxCyto2.png", 2500, 1500},
+		{"Images/ParallaxCyto2.5.png", 2500, 1500},
+		{"Images/ribosome.png", 618, 669}, {"Images/tRNA.png", 318, 474},
+	},
+	"DNA Replication": {
+		{"Images/" + nucleusBackground, 2500, 1500},
+	},
+	"Quorum Sensing": {
+		{"Images/PlasmaBg.png", 2500, 1500}, {"Images/signalC.png", 190, 347},
+		{"Images/inact_receptorC.png", 436, 694}, {"Images/act_receptorC.png", 436, 694},
+		{"Images/inact_TFA.png", 414, 304}, {"Images/act_TFA.png", 414, 304},
+	},
+	"Coupled Expression": {
+		{"Images/CytoBg2.png", 2500, 1500}, {"Images/ribosome.png", 618, 669},
+	},
+	"Nuclear Import": {
+		{"Images/CytoBg1.png", 2500, 1500}, {"Images/act_TFA.png", 414, 304},
+		{"Images/act_TK1.png", 330, 354}, {"Images/act_TK2.png", 378, 394},
+	},
+	"Nuclear Export": {
+		{"Images/" + nucleusBackground, 2500, 1500}, {"Images/rnaPolym.png", 651, 645},
+	},
+}
+
+// Checks every file in the manifest exists and every image has its listed size.
+// Returns one line per problem, naming the file and the scenes that need it.
+func (a *AssetManager) validate() []string {
+	needed := map[AssetSpec][]string{}
+	for scene, specs := range assetManifest {
+		for _, spec := range specs {
+			needed[spec] = append(needed[spec], scene)
+		}
+	}
+	var problems []string
+	for spec, scenes := range needed {
+		sort.Strings(scenes)
+		used := " (needed by " + strings.Join(scenes, ", ") + ")"
+		data, err := a.read(spec.name)
+		if err != nil {
+			problems = append(problems, spec.name+" is missing"+used)
+			continue
+		}
+		if spec.width == 0 {
+			continue
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			problems = append(problems, spec.name+" is not a readable image"+used)
+		} else if config.Width != spec.width || config.Height != spec.height {
+			problems = append(problems, fmt.Sprintf("%s is %dx%d, expected %dx%d%s",
+				spec.name, config.Width, config.Height, spec.width, spec.height, used))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// Everything a scene needs, including the shared files
+func sceneAssets(scene string) []AssetSpec {
+	return append(append([]AssetSpec{}, assetManifest["Shared"]...), assetManifest[scene]...)
+}
+
+// The scene that follows each one on the pathway, so its assets can be loaded while this one is played
+func nextOnPathway(scene string) string {
+	if prokaryoteMode {
+		switch scene {
+		case "Main Menu", "Level Selection":
+			return "Quorum Sensing"
+		case "Quorum Sensing":
+			return "Coupled Expression"
+		}
+	}
+	switch scene {
+	case "Main Menu", "Level Selection":
+		return "Signal Reception"
+	case "Signal Reception":
+		return "Signal Transduction"
+	case "Signal Transduction":
+		return "Nuclear Import"
+	case "Nuclear Import":
+		return "DNA Replication"
+	case "DNA Replication":
+		return "Transcription"
+	case "Transcription":
+		return "Nuclear Export"
+	case "Nuclear Export":
+		return "Translation"
+	}
+	return "Main Menu"
+}
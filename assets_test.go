@@ -0,0 +1,10 @@
+package main
+
+import "testing"
+
+// Every manifest entry must name a real file at the size it was drawn at
+func TestAssetManifestMatchesFiles(t *testing.T) {
+	for _, problem := range newAssetManager("").validate() {
+		t.Error(problem)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Small images are packed into shared pages so sprites drawn together come from the same texture
+const (
+	atlasPageSize = 2048
+	atlasMaxSide  = 512 // Larger images, such as backgrounds, keep their own texture
+	atlasPadding  = 2   // Gap between images so linear filtering does not bleed a neighbour in
+)
+
+// Packs images into pages in rows ("shelves"), starting a new row when one is full
+// and a new page when a page is full
+type Atlas struct {
+	pages  []*ebiten.Image
+	x, y   int
+	rowMax int
+}
+
+// Copies an image into the atlas and returns its region of the page. Images too
+// large to pack are returned as they are.
+func (a *Atlas) add(img *ebiten.Image) *ebiten.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w > atlasMaxSide || h > atlasMaxSide {
+		return img
+	}
+	if a.x+w > atlasPageSize {
+		a.x, a.y, a.rowMax = 0, a.y+a.rowMax+atlasPadding, 0
+	}
+	if len(a.pages) == 0 || a.y+h > atlasPageSize {
+		a.pages = append(a.pages, ebiten.NewImage(atlasPageSize, atlasPageSize))
+		a.x, a.y, a.rowMax = 0, 0, 0
+	}
+	page := a.pages[len(a.pages)-1]
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(a.x), float64(a.y))
+	page.DrawImage(img, op)
+	img.Dispose()
+
+	region := page.SubImage(image.Rect(a.x, a.y, a.x+w, a.y+h)).(*ebiten.Image)
+	a.x += w + atlasPadding
+	a.rowMax = max(a.rowMax, h)
+	return region
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Images can only be drawn once the game loop runs, so the tests run inside one
+type testGame struct {
+	done chan int
+	code int
+}
+
+func (t *testGame) Update() error {
+	select {
+	case t.code = <-t.done:
+		return ebiten.Termination
+	default:
+		return nil
+	}
+}
+
+func (t *testGame) Draw(screen *ebiten.Image) {}
+
+func (t *testGame) Layout(w, h int) (int, int) { return screenWidth, screenHeight }
+
+func TestMain(m *testing.M) {
+	g := &testGame{done: make(chan int, 1)}
+	go func() { g.done <- m.Run() }()
+	if err := ebiten.RunGame(g); err != nil {
+		panic(err)
+	}
+	os.Exit(g.code)
+}
+
+const benchSprites = 500
+
+// Makes one small image per sprite, the way molecules were loaded before the atlas
+func benchImages() []*ebiten.Image {
+	images := make([]*ebiten.Image, benchSprites)
+	for i := range images {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		for p := 0; p < len(img.Pix); p += 4 {
+			img.Pix[p], img.Pix[p+3] = uint8(i), 0xff
+		}
+		images[i] = ebiten.NewImageFromImage(img)
+	}
+	return images
+}
+
+// Draws a sprite per image onto a screen-sized target and reads it back so
+// every iteration waits for the GPU
+func benchmarkDraw(b *testing.B, images []*ebiten.Image) {
+	dst := ebiten.NewImage(screenWidth, screenHeight)
+	sprites := make([]Sprite, len(images))
+	for i, img := range images {
+		x, y := (i*37)%(screenWidth-64), (i*53)%(screenHeight-64)
+		sprites[i] = Sprite{image: img, rect: newRect(float64(x), float64(y), 64, 64), scaleW: 1, scaleH: 1}
+	}
+	pixels := make([]byte, 4*screenWidth*screenHeight)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		dst.Fill(color.Black)
+		for _, s := range sprites {
+			s.draw(dst)
+		}
+		dst.ReadPixels(pixels)
+	}
+}
+
+func BenchmarkDrawSeparateImages(b *testing.B) {
+	benchmarkDraw(b, benchImages())
+}
+
+func BenchmarkDrawAtlas(b *testing.B) {
+	atlas := &Atlas{}
+	images := benchImages()
+	for i, img := range images {
+		images[i] = atlas.add(img)
+	}
+	benchmarkDraw(b, images)
+}
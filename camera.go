@@ -0,0 +1,51 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Maps the logical screen onto the window. The logical screen is scaled uniformly to fit,
+// centred, and the sides that do not fit are left as black bars (letterboxing).
+type Camera struct {
+	scale   float64
+	offsetX float64
+	offsetY float64
+}
+
+var camera = Camera{scale: 1}
+
+// Colour of the bars around the logical screen when the window has a different aspect ratio
+var letterboxColor = color.Black
+
+// Fits the logical screen into a window of the given size in device pixels
+func (c *Camera) fit(windowWidth, windowHeight int) {
+	c.scale = min(float64(windowWidth)/screenWidth, float64(windowHeight)/screenHeight)
+	c.offsetX = (float64(windowWidth) - screenWidth*c.scale) / 2
+	c.offsetY = (float64(windowHeight) - screenHeight*c.scale) / 2
+}
+
+// Transform from logical coordinates to window pixels
+func (c *Camera) geoM() ebiten.GeoM {
+	var m ebiten.GeoM
+	m.Scale(c.scale, c.scale)
+	m.Translate(c.offsetX, c.offsetY)
+	return m
+}
+
+// Converts a window pixel, e.g. the cursor or a touch, to logical coordinates
+func (c *Camera) toLogical(x, y int) (int, int) {
+	return int((float64(x) - c.offsetX) / c.scale), int((float64(y) - c.offsetY) / c.scale)
+}
+
+// Draws the logical screen onto the window
+func (c *Camera) present(screen *ebiten.Image, canvas *ebiten.Image) {
+	bounds := screen.Bounds()
+	c.fit(bounds.Dx(), bounds.Dy())
+	screen.Fill(letterboxColor)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM = c.geoM()
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(canvas, op)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Speed rejected molecules glide back to where they came from, in units per second
+const returnSpeed = 1500
+
+// A molecule the player can pick up with the pointer and drop on a target.
+// Anything embedding a Sprite and a Dragger is one.
+type Draggable interface {
+	bounds() *Rectangle
+	dragging() *Dragger
+}
+
+// Somewhere a draggable can be dropped. drop decides whether the molecule is taken;
+// snap gives where an accepted molecule should rest, if it should move at all.
+type DropTarget interface {
+	zone() Rectangle
+	drop(d Draggable) bool
+	snap(d Draggable) (Vector, bool)
+}
+
+// A draggable or target with a binding site outline. Contact between two of them is tested
+// on the outlines rather than on their bounding boxes.
+type Outlined interface {
+	outline() sim.Polygon
+}
+
+// Drag state of one molecule
+type Dragger struct {
+	is_dragged bool
+	origin     Vector // Where the molecule glides back to when a drop is rejected
+	returning  bool
+	locked     bool // Snapped onto a target, so it can no longer be picked up
+}
+
+func (d *Dragger) dragging() *Dragger {
+	return d
+}
+
+func (s *Sprite) bounds() *Rectangle {
+	return &s.rect
+}
+
+// Puts a molecule straight back at its origin, dropping it if it is held
+func sendHome(d Draggable) {
+	drag := d.dragging()
+	d.bounds().pos = drag.origin
+	drag.is_dragged, drag.returning, drag.locked = false, false, false
+}
+
+// A drop target on a sprite or a fixed area. accept is called with a molecule released
+// over it and reports whether the molecule was taken; reject, if set, is called otherwise.
+// Accepted molecules snap to offset from the target's corner when snaps is set.
+// A target with a shape is touched where that outline is, not anywhere in its rect.
+type Target struct {
+	rect   *Rectangle
+	accept func(d Draggable) bool
+	reject func(d Draggable)
+	offset Vector
+	snaps  bool
+	shape  func() sim.Polygon
+}
+
+func newTarget(rect *Rectangle, accept func(d Draggable) bool) *Target {
+	return &Target{rect: rect, accept: accept}
+}
+
+// Makes accepted molecules snap onto the target at an offset from its corner
+func (t *Target) snapAt(offset Vector) *Target {
+	t.offset, t.snaps = offset, true
+	return t
+}
+
+// Sets a callback for molecules the target turns away, e.g. to explain why
+func (t *Target) onReject(reject func(d Draggable)) *Target {
+	t.reject = reject
+	return t
+}
+
+// Gives the target a binding site outline, worked out each time as the target moves
+func (t *Target) shaped(shape func() sim.Polygon) *Target {
+	t.shape = shape
+	return t
+}
+
+func (t *Target) zone() Rectangle {
+	return *t.rect
+}
+
+func (t *Target) outline() sim.Polygon {
+	if t.shape != nil {
+		return t.shape()
+	}
+	return t.rect.polygon()
+}
+
+func (t *Target) drop(d Draggable) bool {
+	if t.accept(d) {
+		return true
+	}
+	if t.reject != nil {
+		t.reject(d)
+	}
+	return false
+}
+
+func (t *Target) snap(d Draggable) (Vector, bool) {
+	return newVector(t.rect.pos.x+t.offset.x, t.rect.pos.y+t.offset.y), t.snaps
+}
+
+// Dragging for one level: the molecules that can be picked up, the targets they can be
+// dropped on, which one is held and which target it is over
+type DragDrop struct {
+	items   []Draggable
+	targets []DropTarget
+	held    Draggable
+	hover   DropTarget
+}
+
+func newDragDrop(items []Draggable, targets ...DropTarget) *DragDrop {
+	for _, d := range items {
+		d.dragging().origin = d.bounds().pos
+	}
+	return &DragDrop{items: items, targets: targets}
+}
+
+// Picks up, moves and drops molecules, and glides rejected ones home
+func (dd *DragDrop) update() {
+	for _, d := range dd.items {
+		if d.dragging().returning {
+			glideHome(d)
+		}
+	}
+
+	var b_pos = cursorVector()
+	if dd.held == nil && input.isJustPressed() {
+		// Molecules later in the list are drawn on top, so they are picked first
+		for x := len(dd.items) - 1; x >= 0; x-- {
+			d := dd.items[x]
+			drag := d.dragging()
+			if !drag.locked && !drag.returning && rect_point_collision(*d.bounds(), b_pos) {
+				dd.held = d
+				drag.is_dragged = true
+				break
+			}
+		}
+	}
+	if dd.held == nil {
+		dd.hover = nil
+		return
+	}
+
+	rect := dd.held.bounds()
+	rect.pos = newVector(b_pos.x-rect.width/2, b_pos.y-rect.height/2)
+	dd.hover = nil
+	for _, t := range dd.targets {
+		if touching(dd.held, t) {
+			dd.hover = t
+			break
+		}
+	}
+
+	if !input.isPressed() {
+		dd.release()
+	}
+}
+
+// Drops the held molecule on the target under it, if any. Molecules dropped on nothing stay where they are.
+func (dd *DragDrop) release() {
+	d, t := dd.held, dd.hover
+	dd.held, dd.hover = nil, nil
+	drag := d.dragging()
+	drag.is_dragged = false
+	if t == nil {
+		return
+	}
+	if !t.drop(d) {
+		drag.returning = true
+		return
+	}
+	if pos, ok := t.snap(d); ok {
+		d.bounds().pos = pos
+		drag.locked = true
+	}
+}
+
+// Checks if a molecule is over a target, on their outlines when both have one
+func touching(d Draggable, t DropTarget) bool {
+	do, ok1 := d.(Outlined)
+	to, ok2 := t.(Outlined)
+	if ok1 && ok2 {
+		return do.outline().Overlaps(to.outline())
+	}
+	return aabb_collision(*d.bounds(), t.zone())
+}
+
+// Moves a rejected molecule one step toward its origin
+func glideHome(d Draggable) {
+	drag := d.dragging()
+	pos := &d.bounds().pos
+	dx, dy := drag.origin.x-pos.x, drag.origin.y-pos.y
+	dist := math.Hypot(dx, dy)
+	if dist <= returnSpeed*fixedStep {
+		*pos = drag.origin
+		drag.returning = false
+		return
+	}
+	pos.move(dx/dist*returnSpeed, dy/dist*returnSpeed)
+}
+
+// Outlines the target the held molecule would be dropped on
+func (dd *DragDrop) drawHover(screen *ebiten.Image) {
+	if dd.hover == nil {
+		return
+	}
+	if t, ok := dd.hover.(*Target); ok && t.shape != nil {
+		strokePolygon(screen, t.shape(), 4, color.RGBA{255, 220, 60, 255})
+		return
+	}
+	zone := dd.hover.zone()
+	vector.StrokeRect(screen, float32(zone.pos.x)-4, float32(zone.pos.y)-4, float32(zone.width)+8, float32(zone.height)+8, 4, color.RGBA{255, 220, 60, 255}, true)
+}
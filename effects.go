@@ -0,0 +1,165 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Most sparks alive at once. Emitting past this drops sparks instead of growing the pool.
+const maxSparks = 1024
+
+// How an effect looks, looked up by name in emitterSpecs so effects can be retuned
+// without touching the levels that fire them
+type EmitterSpec struct {
+	count   int     // Sparks per burst
+	rate    float64 // Sparks per second while streaming
+	life    float64 // Seconds each spark lasts
+	speed   float64 // Units per second; homing sparks instead take their whole life to arrive
+	scatter float64 // Sparks start anywhere this far from the source
+	size    float32
+	gravity float64 // Downward pull, in units per second squared
+	homing  bool    // Sparks fly from the source to the target instead of outward
+	colours []color.RGBA
+}
+
+var emitterSpecs = map[string]EmitterSpec{
+	// Phosphate groups carried from ATP onto a kinase
+	"phosphate": {count: 6, life: 0.6, scatter: 30, size: 7, homing: true, colours: []color.RGBA{{250, 200, 50, 255}, {240, 140, 40, 255}}},
+	// Nucleoside triphosphates drawn in to RNA polymerase, coloured like their bases
+	"ntp": {rate: 14, life: 0.9, scatter: 220, size: 5, homing: true, colours: []color.RGBA{{220, 60, 60, 255}, {60, 120, 220, 255}, {60, 180, 90, 255}, {240, 200, 60, 255}}},
+	// Sparks where the ribosome joins an amino acid onto the chain
+	"peptide": {count: 24, life: 0.5, speed: 260, size: 4, gravity: 300, colours: []color.RGBA{{255, 250, 210, 255}, {255, 210, 90, 255}}},
+	// Burst when a ligand settles into its receptor's pocket
+	"binding": {count: 32, life: 0.7, speed: 200, size: 5, colours: []color.RGBA{{120, 230, 150, 255}, {240, 240, 255, 255}}},
+}
+
+type Spark struct {
+	pos, vel Vector
+	age      float64
+	life     float64
+	size     float32
+	gravity  float64
+	clr      color.RGBA
+	alive    bool
+}
+
+// Every spark on screen, kept in a fixed pool. Dead sparks' slots are stacked in free
+// and handed out again, so emitting never allocates.
+type Effects struct {
+	sparks [maxSparks]Spark
+	free   []int
+	rng    *rand.Rand // Effects only decorate, so they leave the simulation's random draws alone
+}
+
+var effects = newEffects()
+
+func newEffects() *Effects {
+	e := &Effects{free: make([]int, 0, maxSparks), rng: rand.New(rand.NewSource(1))}
+	e.clear()
+	return e
+}
+
+// Removes every spark, e.g. when the scene changes
+func (e *Effects) clear() {
+	e.free = e.free[:0]
+	for x := maxSparks - 1; x >= 0; x-- {
+		e.sparks[x].alive = false
+		e.free = append(e.free, x)
+	}
+}
+
+func (e *Effects) spawn(spec *EmitterSpec, from, to Vector) {
+	if len(e.free) == 0 {
+		return
+	}
+	x := e.free[len(e.free)-1]
+	e.free = e.free[:len(e.free)-1]
+
+	angle := e.rng.Float64() * 2 * math.Pi
+	dist := spec.scatter * math.Sqrt(e.rng.Float64())
+	pos := newVector(from.x+dist*math.Cos(angle), from.y+dist*math.Sin(angle))
+	var vel Vector
+	if spec.homing {
+		vel = newVector((to.x-pos.x)/spec.life, (to.y-pos.y)/spec.life)
+	} else {
+		speed := spec.speed * (0.5 + e.rng.Float64())
+		vel = newVector(speed*math.Cos(angle), speed*math.Sin(angle))
+	}
+	e.sparks[x] = Spark{
+		pos: pos, vel: vel, life: spec.life, size: spec.size, gravity: spec.gravity,
+		clr: spec.colours[e.rng.Intn(len(spec.colours))], alive: true,
+	}
+}
+
+// Fires one burst of a named effect at a point, or from it toward a target if it homes
+func (e *Effects) burst(name string, from, to Vector) {
+	spec := emitterSpecs[name]
+	for x := 0; x < spec.count; x++ {
+		e.spawn(&spec, from, to)
+	}
+}
+
+// A named effect that streams sparks while it is on. The source and target are
+// read from rects each step, so the stream follows the molecules it joins.
+type Emitter struct {
+	spec     EmitterSpec
+	from, to *Rectangle
+	on       bool
+	carry    float64 // Fraction of a spark owed from earlier steps
+}
+
+func newEmitter(name string, from, to *Rectangle) *Emitter {
+	return &Emitter{spec: emitterSpecs[name], from: from, to: to}
+}
+
+func (em *Emitter) update() {
+	if !em.on {
+		em.carry = 0
+		return
+	}
+	em.carry += em.spec.rate * fixedStep
+	for ; em.carry >= 1; em.carry-- {
+		effects.spawn(&em.spec, rectCentre(*em.from), rectCentre(*em.to))
+	}
+}
+
+func rectCentre(r Rectangle) Vector {
+	return newVector(r.pos.x+r.width/2, r.pos.y+r.height/2)
+}
+
+// Moves and ages every spark, returning dead ones to the pool
+func (e *Effects) update() {
+	for x := range e.sparks {
+		s := &e.sparks[x]
+		if !s.alive {
+			continue
+		}
+		s.age += fixedStep
+		if s.age >= s.life {
+			s.alive = false
+			e.free = append(e.free, x)
+			continue
+		}
+		s.vel.y += s.gravity * fixedStep
+		s.pos.move(s.vel.x, s.vel.y)
+	}
+}
+
+// Draws every spark, fading out over its life
+func (e *Effects) draw(screen *ebiten.Image) {
+	for x := range e.sparks {
+		s := &e.sparks[x]
+		if !s.alive {
+			continue
+		}
+		fade := 1 - s.age/s.life
+		clr := s.clr
+		clr.A = uint8(float64(clr.A) * fade)
+		clr.R, clr.G, clr.B = uint8(float64(clr.R)*fade), uint8(float64(clr.G)*fade), uint8(float64(clr.B)*fade)
+		vector.DrawFilledCircle(screen, float32(s.pos.x), float32(s.pos.y), s.size, clr, true)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Seconds the camera takes to pull back from one stage and close in on the next
+const flightTime = 2.4
+
+// Colour of the cytosol between the stages' backgrounds when the camera pulls back
+var cytosolColor = color.RGBA{250, 215, 190, 255}
+
+// A layer drawn in front of a region's background, shifting further the nearer it is
+type ParallaxLayer struct {
+	image string
+	layer float64
+}
+
+// Where a stage of the pathway lies in the one continuous cell, and what it looks like.
+// Each stage's scene shows exactly its region, so flying the camera into a region lands
+// on the view the scene then takes over.
+type CellRegion struct {
+	scene      string
+	rect       Rectangle
+	background string
+	layers     []ParallaxLayer
+}
+
+// The cell from the plasma membrane at the top down through the cytoplasm into the
+// nucleus, where the gene is copied and transcribed, and back out to the cytoplasm beside
+// it where proteins are made. Regions are drawn in this order when the camera pulls back.
+var cellRegions = []CellRegion{
+	{"Signal Reception", regionAt(1, 0), "PlasmaBg.png",
+		[]ParallaxLayer{{"ParallaxPlasma.png", 4}, {"plasmaMembrane.png", 2}}},
+	{"Signal Transduction", regionAt(1, 1), "CytoBg1.png",
+		[]ParallaxLayer{{"ParallaxCyto1.png", 4}, {"ParallaxCyto1.5.png", 3}}},
+	{"Nuclear Import", regionAt(1, 2), "CytoBg1.png", nil},
+	{"DNA Replication", regionAt(0, 3), nucleusBackground, nil},
+	{"Transcription", regionAt(1, 3), nucleusBackground, nil},
+	{"Nuclear Export", regionAt(2, 3), nucleusBackground, nil},
+	{"Translation", regionAt(2, 2), "CytoBg2.png",
+		[]ParallaxLayer{{"ParallaxCyto2.png", 4}, {"ParallaxCyto2.5.png", 3}}},
+}
+
+// The screen-sized region in a column and row of the cell
+func regionAt(col, row int) Rectangle {
+	return newRect(float64(col*screenWidth), float64(row*screenHeight), screenWidth, screenHeight)
+}
+
+func cellRegion(scene string) (CellRegion, bool) {
+	for _, r := range cellRegions {
+		if r.scene == scene {
+			return r, true
+		}
+	}
+	return CellRegion{}, false
+}
+
+// The camera moving between two stages. It pulls back until both are in view, then
+// closes in on the second, while the level of the first stops and the second loads.
+type Flight struct {
+	view Rectangle // Part of the cell on screen, always the screen's shape
+	path *Sequence
+}
+
+func newFlight(from, to CellRegion) *Flight {
+	f := &Flight{view: from.rect}
+	overview := enclose(from.rect, to.rect)
+	f.path = sequence(
+		tweenRect(&f.view, overview, flightTime/2, easeInOutCubic),
+		tweenRect(&f.view, to.rect, flightTime/2, easeInOutCubic),
+	)
+	return f
+}
+
+func tweenRect(r *Rectangle, to Rectangle, duration float64, ease Ease) *Tween {
+	var from Rectangle
+	return &Tween{
+		duration: duration, ease: ease,
+		start: func() { from = *r },
+		set: func(p float64) {
+			lerp := func(a, b float64) float64 { return a + (b-a)*p }
+			*r = newRect(lerp(from.pos.x, to.pos.x), lerp(from.pos.y, to.pos.y), lerp(from.width, to.width), lerp(from.height, to.height))
+		},
+	}
+}
+
+// Smallest view of the screen's shape holding both rects, with a margin of cell around them
+func enclose(a, b Rectangle) Rectangle {
+	left, top := min(a.pos.x, b.pos.x), min(a.pos.y, b.pos.y)
+	right, bottom := max(a.pos.x+a.width, b.pos.x+b.width), max(a.pos.y+a.height, b.pos.y+b.height)
+	width, height := (right-left)*1.2, (bottom-top)*1.2
+	if width/height < screenWidth/screenHeight {
+		width = height * screenWidth / screenHeight
+	} else {
+		height = width * screenHeight / screenWidth
+	}
+	return newRect((left+right-width)/2, (top+bottom-height)/2, width, height)
+}
+
+// Advances the flight one step and reports whether the camera has arrived
+func (f *Flight) update() bool {
+	return f.path.update()
+}
+
+// Draws every region of the cell whose art is already decoded, as the camera sees it
+func (f *Flight) draw(screen *ebiten.Image) {
+	screen.Fill(cytosolColor)
+	cursor := cursorVector()
+	for _, region := range cellRegions {
+		// Drawn as the region's scene draws them, so nothing moves when the scene takes over
+		f.drawSprite(screen, region, region.background, Vector{}, 0.5)
+		for _, l := range region.layers {
+			pos := parallaxPos(region.scene, l.layer, cursor)
+			f.drawSprite(screen, region, l.image, pos, (l.layer+0.5)/(2*l.layer), l.layer)
+		}
+	}
+}
+
+// Draws an image the way a sprite at a position in the region's scene is drawn, then
+// moves it to where the region is in view
+func (f *Flight) drawSprite(screen *ebiten.Image, region CellRegion, name string, pos Vector, scale float64, params ...interface{}) {
+	// Art still loading is left out rather than waited for
+	if !assets.cached([]AssetSpec{{name: "Images/" + name}}) {
+		return
+	}
+	s := Sprite{image: assets.image(name), rect: Rectangle{pos: pos}, scaleW: scale, scaleH: scale}
+	zoom := screenWidth / f.view.width
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM = s.geoM(params...)
+	op.GeoM.Translate(region.rect.pos.x-f.view.pos.x, region.rect.pos.y-f.view.pos.y)
+	op.GeoM.Scale(zoom, zoom)
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(s.image, op)
+}
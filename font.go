@@ -3,10 +3,8 @@ package main
 import (
 	"image/color"
 	"log"
-	"os"
 
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/opentype"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text"
@@ -16,20 +14,8 @@ type Font struct {
 	face font.Face
 }
 
-func newFont(path string, size int) Font {
-	font_data, err := os.ReadFile(path)
-	if err != nil {
-		log.Fatal(err)
-	}
-	tt, err := opentype.Parse(font_data)
-	if err != nil {
-		log.Fatal(err)
-	}
-	font_face, err := opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    float64(size),
-		DPI:     72,
-		Hinting: font.HintingVertical,
-	})
+func newFont(name string, size int) Font {
+	font_face, err := assets.font(name, size)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -38,6 +24,6 @@ func newFont(path string, size int) Font {
 	}
 }
 
-func (f *Font) drawFont(surface *ebiten.Image, str string, x int, y int, clr color.Color) {
-	text.Draw(surface, str, f.face, x, y, clr)
+func (f *Font) drawFont(surface *ebiten.Image, str string, x float64, y float64, clr color.Color) {
+	text.Draw(surface, str, f.face, int(x), int(y), clr)
 }
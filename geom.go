@@ -1,20 +1,26 @@
 package main
 
+import "github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+
+// Length of one fixed simulation step in seconds. Motion is integrated in steps of this
+// length whatever the tick rate, so velocities are given in units per second.
+const fixedStep = sim.TickSeconds
+
 // Storing coordinates
 type Vector struct {
-	x int
-	y int
+	x float64
+	y float64
 }
 
 // Storing a rectangle's data
 type Rectangle struct {
 	pos    Vector
-	width  int
-	height int
+	width  float64
+	height float64
 }
 
 // Creating a vector
-func newVector(x int, y int) Vector {
+func newVector(x float64, y float64) Vector {
 	return Vector{
 		x: x,
 		y: y,
@@ -22,7 +28,7 @@ func newVector(x int, y int) Vector {
 }
 
 // Creating a rectangle
-func newRect(x int, y int, width int, height int) Rectangle {
+func newRect(x float64, y float64, width float64, height float64) Rectangle {
 	return Rectangle{
 		pos:    newVector(x, y),
 		width:  width,
@@ -30,6 +36,12 @@ func newRect(x int, y int, width int, height int) Rectangle {
 	}
 }
 
+// Moves a position by a velocity, in logical units per second, over one fixed step
+func (v *Vector) move(vx float64, vy float64) {
+	v.x += vx * fixedStep
+	v.y += vy * fixedStep
+}
+
 // Check if a point and a rectangle collide
 func rect_point_collision(rect Rectangle, point Vector) bool {
 	if rect.pos.x <= point.x && rect.pos.x+rect.width >= point.x &&
@@ -49,3 +61,11 @@ func aabb_collision(rect1 Rectangle, rect2 Rectangle) bool {
 	}
 	return false
 }
+
+// Outline of a rectangle, for checks against binding sites
+func (r Rectangle) polygon() sim.Polygon {
+	return sim.Polygon{
+		{X: r.pos.x, Y: r.pos.y}, {X: r.pos.x + r.width, Y: r.pos.y},
+		{X: r.pos.x + r.width, Y: r.pos.y + r.height}, {X: r.pos.x, Y: r.pos.y + r.height},
+	}
+}
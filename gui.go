@@ -1,47 +1,44 @@
 package main
 
 import (
-	"fmt"
 	"image/color"
-
+	"math"
 	"strings"
 
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 type ButtonFunc func(*Game)
 
-type GUI interface {
+// Anything a level draws, usually as a node of its scene graph
+type Drawable interface {
 	draw(screen *ebiten.Image)
-	update(params ...interface{})
-	scaleToScreen()
-	//getStructType(GUI) reflect.Type
 }
 
-//func getStructType(g GUI) reflect.Type {
-//	return reflect.TypeOf(g)
-//}
+// Anything a level steps, from player input or by itself
+type Updatable interface {
+	update(params ...interface{})
+}
 
 // Create Sprite struct with fields for image, second image (optional),
-// rectangle, scale factors, matrix draw option, + original images
+// rectangle, scale factors and matrix draw option. Images are the shared
+// textures from the asset manager; scale is applied when drawing.
 type Sprite struct {
-	image       *ebiten.Image
-	image_2     *ebiten.Image
-	rect        Rectangle
-	scaleW      float64
-	scaleH      float64
-	op          ebiten.GeoM
-	origImage   *ebiten.Image
-	origImage_2 *ebiten.Image
+	image   *ebiten.Image
+	image_2 *ebiten.Image
+	rect    Rectangle
+	scaleW  float64
+	scaleH  float64
+	op      ebiten.GeoM
 }
 
 type Button struct {
 	//CommonDraw
 	Sprite
-	cmd ButtonFunc
+	cmd   ButtonFunc
+	label string
 }
 
 type VolButton struct {
@@ -52,34 +49,62 @@ type VolButton struct {
 
 type Signal struct {
 	Sprite
-	is_dragged bool
-	signalType string
+	Dragger
+	shape sim.Polygon // Binding site, around the middle of the signal
+	angle float64     // Clockwise turn in degrees; the binding site only fits its pocket at the right angle
+}
+
+// Degrees the signal turns each time the player rotates it
+const rotateStep = 30
+
+type Cargo struct {
+	Sprite
+	Dragger
+	is_bound bool
+	signal   string // Localization signal carried, "NLS", "NES" or "" for none
+	name     string
 }
 
 type Receptor struct {
 	Sprite
-	is_touching_signal bool // MAY move outside to variable of plasma struct to let signal access it
-	receptorType       string
+	receptorType string
+	pocket       sim.Polygon // Binding site, around the pocket's middle in the receptor's head
+	anchor       *Vector     // Place on the membrane it sways about as the view shifts, nil if it stays put
+	conformation *Conformation
 }
 
+// Speeds of the moving molecules, in base screen units per second
+const (
+	descendSpeed    = 180 // Activated molecules sinking toward the nucleus
+	polymeraseSpeed = 300 // RNA polymerase and ribosomes leaving the end of the strand
+)
+
+// Seconds RNA polymerase and ribosomes take to clamp on at the start and to step one codon
+const (
+	clampTime       = 2.0
+	translocateTime = 0.5
+)
+
 type Kinase struct {
 	Sprite
-	is_moving     bool
-	is_clicked_on bool
-	delta         int
-	kinaseType    string
+	is_moving    bool
+	kinaseType   string
+	anchor       *Vector // Place under the membrane a receptor's kinase sways about until it is released
+	conformation *Conformation
 }
 
 type TFA struct {
 	Sprite
-	is_active bool
-	tfaType   string
+	is_active    bool
+	tfaType      string
+	conformation *Conformation
 }
 
 type Transcript struct {
 	Sprite
-	codon string
-	isRNA bool
+	codon      string
+	isRNA      bool
+	polymerase *RNAPolymerase // Polymerase an RNA transcript trails behind
 }
 
 type Template struct {
@@ -91,21 +116,26 @@ type Template struct {
 
 type RNAPolymerase struct {
 	Sprite
-	next bool
+	next    bool
+	clamped bool   // Has started clamping onto the DNA at the promoter
+	moving  bool   // Is sliding to the next codon
+	tfa     *TFA   // TF the polymerase binds behind at the promoter
+	onCodon func() // Called once it has moved on to the next codon
 }
 
 type Nucleobase struct {
 	Sprite
-	baseType   string
-	index      int
-	isTemplate bool
+	baseType    string
+	index       int
+	isTemplate  bool
+	is_mismatch bool
 }
 
 type CodonChoice struct {
 	Sprite
-	codon      string
-	bases      [3]Nucleobase
-	is_dragged bool
+	Dragger
+	codon string
+	bases [3]Nucleobase
 }
 
 type tRNA struct {
@@ -115,11 +145,21 @@ type tRNA struct {
 
 type Ribosome struct {
 	Sprite
+	docking bool          // Is clamping onto the start codon
+	moving  bool          // Is translocating to the next codon
+	onCodon func(g *Game) // Called once it has finished with a codon
+}
+
+type Enzyme struct {
+	Sprite
+	name          string
+	is_clicked_on bool
 }
 
 type Parallax struct {
 	Sprite
 	layer float64
+	scene string // Scene whose cursor sway the layer follows
 }
 
 type InfoPage struct {
@@ -144,93 +184,55 @@ func newSprite(params ...interface{}) Sprite {
 		rect := params[2].(Rectangle)
 		scaleW, scaleH := params[3].(float64), params[3].(float64)
 
-		// Store original image from the parameter to use for scaling in fullscreen
-		var origImg, _, err1 = ebitenutil.NewImageFromFile(loadFile(path1))
-		var origImg2, _, err2 = ebitenutil.NewImageFromFile(loadFile(path2))
-
-		// Check error if image does not exist
-		if err1 != nil {
-			fmt.Println("Error parsing date:", err)
-		}
-		if err2 != nil {
-			fmt.Println("Error parsing date:", err)
-		}
-
-		// Scale original image from parameter based on scaling factors
-		var img_1 = scaleImage(origImg, scaleW, scaleH)
-		var img_2 = scaleImage(origImg2, scaleW, scaleH)
-
 		// Return Sprite struct
 		return Sprite{
-			image:       img_1,
-			image_2:     img_2,
-			rect:        rect,
-			scaleW:      scaleW,
-			scaleH:      scaleH,
-			origImage:   origImg,
-			origImage_2: origImg2,
+			image:   assets.image(path1),
+			image_2: assets.image(path2),
+			rect:    rect,
+			scaleW:  scaleW,
+			scaleH:  scaleH,
 		}
 
 	} else { // if 3 parameters passed, no second image needed.
 		path := params[0].(string)
 		rect := params[1].(Rectangle)
 		scaleW, scaleH := params[2].(float64), params[2].(float64)
-		var origImg, _, err1 = ebitenutil.NewImageFromFile(loadFile(path))
-		if err1 != nil {
-			fmt.Println("Error parsing date:", err)
-		}
-		var img_1 = scaleImage(origImg, scaleW, scaleH)
+		var img_1 = assets.image(path)
 		return Sprite{
-			image:       img_1,
-			image_2:     img_1,
-			rect:        rect,
-			scaleW:      scaleW,
-			scaleH:      scaleH,
-			origImage:   origImg,
-			origImage_2: origImg,
+			image:   img_1,
+			image_2: img_1,
+			rect:    rect,
+			scaleW:  scaleW,
+			scaleH:  scaleH,
 		}
 	}
 }
 
-// General function for scaling any image using the parameters for scaling factors
-func scaleImage(img *ebiten.Image, scaleFactorW float64, scaleFactorH float64) *ebiten.Image {
-	bounds := img.Bounds()
-	width := int(float64(bounds.Dx()) * scaleFactorW)
-	height := int(float64(bounds.Dy()) * scaleFactorH)
-	scaled := ebiten.NewImage(width, height) // Creates empty new image with desired width/height
-	ops := &ebiten.DrawImageOptions{}        // Create new DrawImageOptions to resize img from parameter
-	ops.GeoM.Scale(scaleFactorW, scaleFactorH)
-	scaled.DrawImage(ebiten.NewImageFromImage(img), ops) // Draws resized img onto the empty scaled image
-	return scaled                                        // Returns scaled with img drawn onto new bounds
-}
-
-// Method of Sprite struct, calls scaleImage() on sprite image using
-// sprite geometry (op) and scaling factors
-func (s *Sprite) scaleToScreen() {
-	s.op = ebiten.GeoM{}
-	if ebiten.IsFullscreen() {
-		s.image = scaleImage(s.origImage, 1.15*s.scaleW*float64(widthRatio), 1.2*s.scaleH*float64(heightRatio))
-		//s.image = scaleImage(s.origImage, s.scaleW*float64(widthRatio), s.scaleH*float64(heightRatio))
-	} else {
-		s.image = scaleImage(s.origImage, s.scaleW, s.scaleH)
-	}
-	// NEVER TRY THIS CODE- IT BREAKS THE COMPUTER!!! - s.image = scaleImage(s.origImage, s.scaleW*float64(baseScreenWidth/screenWidth), s.scaleH*float64(baseScreenHeight/screenHeight))
-}
-
 func (s Sprite) draw(screen *ebiten.Image, params ...interface{}) {
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM = s.op
-	op.GeoM.Translate(float64(s.rect.pos.x), float64(s.rect.pos.y))
-	if len(params) == 0 {
-		screen.DrawImage(s.image, op)
+	if len(params) > 1 {
+		return
 	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM = s.geoM(params...)
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(s.image, op)
+}
+
+// Where the sprite's texture lands on screen. When a parallax layer is given, it is drawn
+// larger the nearer it is.
+func (s Sprite) geoM(params ...interface{}) ebiten.GeoM {
+	var m ebiten.GeoM
+	// Scale the texture on the GPU rather than keeping a resized copy
+	m.Scale(s.scaleW, s.scaleH)
+	m.Concat(s.op)
+	m.Translate(float64(s.rect.pos.x), float64(s.rect.pos.y))
 	if len(params) == 1 {
 		layer := params[0].(float64)
 		scaleW := (layer + 0.5) / (layer)
 		scaleH := (layer + 0.5) / (layer)
-		op.GeoM.Scale(scaleW, scaleH)
-		screen.DrawImage(s.image, op)
+		m.Scale(scaleW, scaleH)
 	}
+	return m
 }
 
 func newStillImage(path string, rect Rectangle) StillImage {
@@ -244,8 +246,6 @@ func (s StillImage) draw(screen *ebiten.Image) {
 	s.Sprite.draw(screen)
 }
 
-func (s StillImage) update(params ...interface{}) {}
-
 func newInfoPage(path1 string, path2 string, rect Rectangle, stat string) InfoPage {
 	sprite := newSprite(path1, path2, rect, 1.0)
 	return InfoPage{
@@ -255,9 +255,8 @@ func newInfoPage(path1 string, path2 string, rect Rectangle, stat string) InfoPa
 }
 
 func (i *InfoPage) update(params ...interface{}) {
-	var x_c, y_c = ebiten.CursorPosition()
-	var b_pos = newVector(x_c, y_c)
-	if rect_point_collision(i.rect, b_pos) && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+	var b_pos = cursorVector()
+	if rect_point_collision(i.rect, b_pos) && input.isJustPressed() {
 		if i.status == "btn" {
 			i.status = "pg"
 			i.Sprite.rect = newRect(0, 0, screenWidth, screenHeight)
@@ -265,10 +264,7 @@ func (i *InfoPage) update(params ...interface{}) {
 			i.status = "btn"
 			i.Sprite.rect = newRect(850, 0, 165, 165)
 		}
-		temp_img := i.Sprite.origImage
-		i.Sprite.origImage = i.Sprite.origImage_2
-		i.Sprite.origImage_2 = temp_img
-		i.Sprite.scaleToScreen()
+		i.Sprite.image, i.Sprite.image_2 = i.Sprite.image_2, i.Sprite.image
 	}
 }
 
@@ -280,33 +276,34 @@ func (i InfoPage) draw(screen *ebiten.Image) {
 	}
 }
 
-func newParallax(path string, rect Rectangle, layer float64) Parallax {
+func newParallax(scene string, path string, rect Rectangle, layer float64) Parallax {
 	sprite := newSprite(path, rect, (layer+0.5)/(2*layer))
 	return Parallax{
 		Sprite: sprite,
 		layer:  layer,
+		scene:  scene,
 	}
 }
 
-func (p *Parallax) update(params ...interface{}) {
-	var x_c, y_c = ebiten.CursorPosition()
-	var l = int(p.layer)
+// Where a layer of a scene's parallax sits for a cursor position; nearer layers move further
+func parallaxPos(scene string, layer float64, cursor Vector) Vector {
+	var x_c, y_c = cursor.x, cursor.y
+	var l = layer
 	switch scene {
 	case "Main Menu":
-		p.rect.pos.x = -5 * (x_c + 75) / (6 * l)
-		p.rect.pos.y = -5 * (y_c + 100) / (7 * l)
+		return newVector(-5*(x_c+75)/(6*l), -5*(y_c+100)/(7*l))
 	case "Signal Reception":
-		p.rect.pos.x = -6 * (x_c + 100) / (7 * l)
-		p.rect.pos.y = -2 * (y_c + 100) / (3 * l)
+		return newVector(-6*(x_c+100)/(7*l), -2*(y_c+100)/(3*l))
 	case "Signal Transduction":
-		p.rect.pos.x = -5 * (x_c + 80) / (7 * l)
-		p.rect.pos.y = -3 * (y_c + 100) / (5 * l)	
+		return newVector(-5*(x_c+80)/(7*l), -3*(y_c+100)/(5*l))
 	case "Translation":
-		p.rect.pos.x = -5 * (x_c + 95) / (7 * l)
-		p.rect.pos.y = -3 * (y_c + 100) / (5 * l)
+		return newVector(-5*(x_c+95)/(7*l), -3*(y_c+100)/(5*l))
 	}
-	//p.rect.pos.x = (x_c - 625) / (2*l);
-	//p.rect.pos.y = (y_c - 375) / (2*l);
+	return newVector(0, 0)
+}
+
+func (p *Parallax) update(params ...interface{}) {
+	p.rect.pos = parallaxPos(p.scene, p.layer, cursorVector())
 }
 
 func (p Parallax) draw(screen *ebiten.Image) {
@@ -327,9 +324,8 @@ func (b *Button) update(params ...interface{}) {
 		if !ok {
 			return
 		}
-		var x_c, y_c = ebiten.CursorPosition()
-		var b_pos = newVector(x_c, y_c)
-		if rect_point_collision(b.rect, b_pos) && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		var b_pos = cursorVector()
+		if rect_point_collision(b.rect, b_pos) && input.isJustPressed() {
 			b.cmd(g)
 		}
 	}
@@ -337,6 +333,19 @@ func (b *Button) update(params ...interface{}) {
 
 func (b Button) draw(screen *ebiten.Image) {
 	b.Sprite.draw(screen)
+	if b.label != "" {
+		defaultFont.drawFont(screen, b.label, b.rect.pos.x+20, b.rect.pos.y+70, color.RGBA{25, 0, 90, 255})
+	}
+}
+
+// Button drawn from a plain image with its label written over it, for buttons without their own art
+func newLabelButton(path string, rect Rectangle, cmd ButtonFunc, label string) Button {
+	sprite := newSprite(path, rect, 0.5)
+	return Button{
+		Sprite: sprite,
+		cmd:    cmd,
+		label:  label,
+	}
 }
 
 func newVolButton(path string, rect Rectangle, cmd ButtonFunc, player audio.Player) VolButton {
@@ -372,166 +381,158 @@ func (v *VolButton) SwitchVol(onOff string) {
 	v.status = onOff
 	if v.status == "OFF" {
 		v.player.Pause()
-		sprite := newSprite("volButtonOff.png", v.rect, 1.0)
-		v.Sprite = sprite
+		v.Sprite.image = assets.image("volButtonOff.png")
 	} else {
 		v.player.Play()
-		sprite := newSprite("volButtonOn.png", v.rect, 1.0)
-		v.Sprite = sprite
+		v.Sprite.image = assets.image("volButtonOn.png")
 	}
 }
 
-func newSignal(path string, rect Rectangle) Signal {
-	sprite := newSprite(path, rect, 0.5)
-
+// Signal drawn at runtime from its binding site
+func newSignal(shape sim.Polygon, rect Rectangle) Signal {
+	img := ligandImage(shape)
 	return Signal{
-		Sprite:     sprite,
-		is_dragged: false,
+		Sprite: Sprite{image: img, image_2: img, rect: rect, scaleW: 1, scaleH: 1},
+		shape:  shape,
 	}
 }
 
-func (s *Signal) update(params ...interface{}) {
-	x_c, y_c := ebiten.CursorPosition()
-	var b_pos = newVector(x_c, y_c)
+// Signals are moved by the level's drag and drop
 
-	if !s.is_dragged {
-		if rect_point_collision(s.rect, b_pos) && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-			s.is_dragged = true
-		}
-	} else if s.is_dragged {
-		if s.rect.pos.y <= receptionStruct.receptorB.rect.pos.y && b_pos.y <= receptionStruct.receptorB.rect.pos.y-25 {
-			s.Sprite.drag(true, true, b_pos)
-		} else {
-			s.Sprite.drag(true, false, b_pos)
-		}
-	}
+// Middle of the drawn signal, which it turns about and its binding site is centred on
+func (s *Signal) centre() Vector {
+	w, h := float64(s.image.Bounds().Dx())*s.scaleW, float64(s.image.Bounds().Dy())*s.scaleH
+	return newVector(s.rect.pos.x+w/2, s.rect.pos.y+h/2)
 }
 
-func (s *Sprite) drag(x_drag, y_drag bool, b_pos Vector) {
-	if x_drag {
-		s.rect.pos.x = b_pos.x - (s.rect.width / 2)
-	}
-	if y_drag {
-		s.rect.pos.y = b_pos.y - (s.rect.height / 2)
-	}
+func (s *Signal) rotate(degrees float64) {
+	s.angle = math.Mod(s.angle+degrees+360, 360)
 }
 
-func (s *Signal) bind(r *Receptor) {
-	s.is_dragged = false
-	if r.receptorType == "receptorA" || r.receptorType == "receptorD" {
-		s.rect.pos.x, s.rect.pos.y = r.rect.pos.x+80, r.rect.pos.y
-	} else {
-		s.rect.pos.x, s.rect.pos.y = r.rect.pos.x+60, r.rect.pos.y
-	}
+// Binding site where the signal is on screen, turned with it
+func (s *Signal) outline() sim.Polygon {
+	c := s.centre()
+	return s.shape.Rotate(s.angle).Translate(c.x, c.y)
 }
 
 func (s Signal) draw(screen *ebiten.Image) {
+	c := s.centre()
+	s.op.Reset()
+	s.op.Translate(s.rect.pos.x-c.x, s.rect.pos.y-c.y)
+	s.op.Rotate(s.angle * math.Pi / 180)
+	s.op.Translate(c.x-s.rect.pos.x, c.y-s.rect.pos.y)
 	s.Sprite.draw(screen)
+	strokePolygon(screen, s.outline(), 2, color.RGBA{255, 255, 255, 160})
+}
+
+func newCargo(path string, rect Rectangle, scale float64, signal string, name string) Cargo {
+	sprite := newSprite(path, rect, scale)
+	return Cargo{
+		Sprite:  sprite,
+		Dragger: Dragger{origin: rect.pos},
+		signal:  signal,
+		name:    name,
+	}
+}
+
+// Cargo is moved by the level's drag and drop
+
+// Sends refused cargo straight back to where it started
+func (c *Cargo) refuse() {
+	sendHome(c)
+}
+
+func (c Cargo) draw(screen *ebiten.Image) {
+	c.Sprite.draw(screen)
+	label := c.name
+	if c.signal != "" {
+		label += " (" + c.signal + ")"
+	}
+	defaultFont.drawFont(screen, label, c.rect.pos.x, c.rect.pos.y+c.rect.height+25, color.Black)
 }
 
 func newReceptor(path1 string, path2 string, rect Rectangle, rtype string) Receptor {
 	sprite := newSprite(path1, path2, rect, 0.52)
 	return Receptor{
-		Sprite:             sprite,
-		is_touching_signal: false,
-		receptorType:       rtype,
+		Sprite:       sprite,
+		receptorType: rtype,
+		conformation: newConformation(sprite.image, sprite.image_2),
+	}
+}
+
+// Receptor drawn at runtime around one of the run's generated pockets, swaying on the membrane
+func newShapedReceptor(site sim.ReceptorSite, anchor Vector) Receptor {
+	inactive, active := receptorImages(site.Pocket)
+	return Receptor{
+		Sprite:       Sprite{image: inactive, image_2: active, rect: newRect(anchor.x, anchor.y, receptorWidth, receptorHeight), scaleW: 1, scaleH: 1},
+		receptorType: site.Name,
+		pocket:       site.Pocket,
+		anchor:       &anchor,
+		conformation: newConformation(inactive, active),
 	}
 }
 
+// Pocket where the receptor is on screen
+func (r *Receptor) site() sim.Polygon {
+	return r.pocket.Translate(r.rect.pos.x+pocketCentreX, r.rect.pos.y+pocketCentreY)
+}
+
 func (r Receptor) draw(screen *ebiten.Image) {
 	r.Sprite.draw(screen)
 }
 
 func (r *Receptor) update(params ...interface{}) {
-	var x_c, y_c = ebiten.CursorPosition()
-	switch r.receptorType {
-	case "receptorA":
-		r.rect.pos.x = ((-5 * (x_c + 100) / (9 * 1)) + (screenWidth * 1 / 7)) * screenWidth / baseScreenWidth
-		r.rect.pos.y = ((-1 * (y_c + 100) / (4 * 1)) + 450) * screenHeight / baseScreenHeight
-	case "receptorB":
-		r.rect.pos.x = ((-5 * (x_c + 100) / (9 * 1)) + (screenWidth * 4 / 7)) * screenWidth / baseScreenWidth
-		r.rect.pos.y = ((-1 * (y_c + 100) / (4 * 1)) + 400) * screenHeight / baseScreenHeight
-	case "receptorC":
-		r.rect.pos.x = ((-5 * (x_c + 100) / (9 * 1)) + (screenWidth * 7 / 7)) * screenWidth / baseScreenWidth
-		r.rect.pos.y = ((-1 * (y_c + 100) / (4 * 1)) + 400) * screenHeight / baseScreenHeight
-	case "receptorD":
-		r.rect.pos.x = ((-5 * (x_c + 100) / (9 * 1)) + (screenWidth * 9 / 7)) * screenWidth / baseScreenWidth
-		r.rect.pos.y = ((-1 * (y_c + 100) / (4 * 1)) + 450) * screenHeight / baseScreenHeight
-	}
-	if aabb_collision(receptionStruct.signal.rect, r.rect) {
-		r.is_touching_signal = true
-	} else {
-		r.is_touching_signal = false
+	if r.anchor != nil {
+		r.rect.pos = swayed(*r.anchor, 4)
+	}
+	if frame := r.conformation.update(); frame != nil {
+		r.image = frame
 	}
 }
 
+// Position about an anchor on the membrane as the view shifts with the cursor.
+// Layers further back, with a larger depth, move less up and down.
+func swayed(anchor Vector, depth float64) Vector {
+	var x_c, y_c = cursorVector().x, cursorVector().y
+	return newVector((-5*(x_c+100)/9)+anchor.x, (-1*(y_c+100)/depth)+anchor.y)
+}
+
+// Binding the signal shifts the receptor into its active shape
 func (r *Receptor) animate() {
-	r.Sprite.origImage = r.Sprite.origImage_2
-	r.Sprite.scaleToScreen()
+	r.conformation.play("activate")
+}
+
+// Shows the receptor inactive again once its signal falls off
+func (r *Receptor) rest() {
+	r.conformation.play("rest")
 }
 
 func newKinase(path1 string, path2 string, rect Rectangle, ktype string) Kinase {
 	sprite := newSprite(path1, path2, rect, 0.52)
 	return Kinase{
-		Sprite:        sprite,
-		is_moving:     false,
-		is_clicked_on: false,
-		delta:         4,
-		kinaseType:    ktype,
+		Sprite:       sprite,
+		is_moving:    false,
+		kinaseType:   ktype,
+		conformation: newConformation(sprite.image, sprite.image_2),
 	}
 }
 
+// Receptors' kinases sway with the membrane until they are released and sink away.
+// Kinases in the cytoplasm are moved by the physics layer instead.
 func (k *Kinase) update(params ...interface{}) {
-	var x_c, y_c = ebiten.CursorPosition()
-	var b_pos = newVector(x_c, y_c)
+	if frame := k.conformation.update(); frame != nil {
+		k.image = frame
+	}
 	if strings.Contains(k.kinaseType, "temp_tk1") {
 		if !k.is_moving {
-			var x_c, y_c = ebiten.CursorPosition()
-			switch k.kinaseType {
-			case "temp_tk1A":
-				k.rect.pos.x = ((-5 * (x_c + 100) / (9 * 1)) + (screenWidth * 1 / 7)) * screenWidth / baseScreenWidth
-				k.rect.pos.y = ((-1 * (y_c + 100) / (5 * 1)) + 650) * screenHeight / baseScreenHeight
-			case "temp_tk1B":
-				k.rect.pos.x = ((-5 * (x_c + 100) / (9 * 1)) + (screenWidth * 4 / 7)) * screenWidth / baseScreenWidth
-				k.rect.pos.y = ((-1 * (y_c + 100) / (5 * 1)) + 600) * screenHeight / baseScreenHeight
-			case "temp_tk1C":
-				k.rect.pos.x = ((-5 * (x_c + 100) / (9 * 1)) + (screenWidth * 7 / 7)) * screenWidth / baseScreenWidth
-				k.rect.pos.y = ((-1 * (y_c + 100) / (5 * 1)) + 600) * screenHeight / baseScreenHeight
-			case "temp_tk1D":
-				k.rect.pos.x = ((-5 * (x_c + 100) / (9 * 1)) + (screenWidth * 9 / 7)) * screenWidth / baseScreenWidth
-				k.rect.pos.y = ((-1 * (y_c + 100) / (5 * 1)) + 650) * screenHeight / baseScreenHeight
+			if k.anchor != nil {
+				k.rect.pos = swayed(*k.anchor, 5)
 			}
 		} else if k.is_moving {
 			if k.rect.pos.y <= screenHeight {
 				k.descend()
 			}
 		}
-	} else if !k.is_clicked_on && k.is_moving {
-		if k.rect.pos.y <= 400*(screenHeight/750) && k.kinaseType == "tk2" {
-			k.descend()
-		} else if k.rect.pos.y <= 50*(screenHeight/750) && k.kinaseType == "tk1" {
-			k.descend()
-		} else {
-			if ebiten.IsFullscreen() {
-				k.rect.pos.x += k.delta * int(widthRatio)
-			} else {
-				k.rect.pos.x += k.delta
-			}
-		}
-	}
-	if k.kinaseType == "tk1" {
-		if rect_point_collision(k.rect, b_pos) && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && aabb_collision(k.rect, transductionStruct.tk2.rect) {
-			k.is_clicked_on = true
-		}
-	} else if k.kinaseType == "tk2" {
-		if rect_point_collision(k.rect, b_pos) && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && aabb_collision(k.rect, transductionStruct.tfa.rect) {
-			k.is_clicked_on = true
-		}
-	}
-	if k.rect.pos.x+k.rect.width >= screenWidth {
-		k.delta = -4
-	} else if k.rect.pos.x <= 0 {
-		k.delta = 4
 	}
 }
 
@@ -539,34 +540,27 @@ func (k Kinase) draw(screen *ebiten.Image) {
 	k.Sprite.draw(screen)
 }
 
+// Phosphorylates the kinase, with phosphate groups flying in from ATP beside it
 func (k *Kinase) activate() {
-	if k.kinaseType == "tk2" {
-		k.rect.pos.y -= 3 * (screenHeight / baseScreenHeight)
-	}
+	centre := rectCentre(k.rect)
+	effects.burst("phosphate", newVector(centre.x-120, centre.y+90), centre)
 	if strings.Contains(k.kinaseType, "temp_tk1") && !k.is_moving {
-		k.rect.pos.y -= 3 * (screenHeight / baseScreenHeight)
+		// Kicks off the receptor for one step before it starts sinking
+		k.rect.pos.move(0, -descendSpeed)
 	}
 	k.animate()
 	k.is_moving = true
 }
 
 func (k *Kinase) descend() {
-	if ebiten.IsFullscreen() {
-		k.rect.pos.y += 4
-	} else {
-		k.rect.pos.y += 3
-	}
+	k.rect.pos.move(0, descendSpeed)
 }
 
 func (k *Kinase) animate() {
-	k.Sprite.origImage = k.Sprite.origImage_2
-	k.Sprite.scaleToScreen()
+	k.conformation.play("activate")
 }
 
 func (t *TFA) activate() {
-	if t.tfaType == "tfa1" {
-		t.rect.pos.y -= 3 * (screenHeight / 750)
-	}
 	t.animate()
 	t.is_active = true
 }
@@ -574,33 +568,28 @@ func (t *TFA) activate() {
 func newTFA(path1 string, path2 string, rect Rectangle, tfaType string) TFA {
 	sprite := newSprite(path1, path2, rect, 0.52)
 	return TFA{
-		Sprite:    sprite,
-		is_active: false,
-		tfaType:   tfaType,
+		Sprite:       sprite,
+		is_active:    false,
+		tfaType:      tfaType,
+		conformation: newConformation(sprite.image, sprite.image_2),
 	}
 }
 
 func (t *TFA) update(params ...interface{}) {
+	if frame := t.conformation.update(); frame != nil {
+		t.image = frame
+	}
+	// TFs in the cytoplasm are moved by the physics layer
 	if t.is_active {
-		if t.rect.pos.y <= screenHeight && t.tfaType == "tfa1" {
-			t.rect.pos.y += 3 * (screenHeight / 750)
-		}
-		if t.tfaType == "tfa2" {
-			rnaPolymPos := transcriptionStruct.rnaPolymerase.rect.pos
-			if rnaPolymPos.x >= 80 {
-				t.rect.pos.x = rnaPolymPos.x + 60
-				t.rect.pos.y = rnaPolymPos.y + 115
-			} else if t.rect.pos.y <= 450 {
-				t.rect.pos.y += 4 * (screenHeight / 750)
-				t.rect.pos.x -= 2 * (screenWidth / 1250)
-			}
+		// Once the polymerase arrives the level attaches the TF to it in the scene graph
+		if t.tfaType == "tfa2" && t.rect.pos.y <= 450 {
+			t.rect.pos.move(-120, 240)
 		}
 	}
 }
 
 func (t *TFA) animate() {
-	t.Sprite.origImage = t.Sprite.origImage_2
-	t.Sprite.scaleToScreen()
+	t.conformation.play("activate")
 }
 
 func (t TFA) draw(screen *ebiten.Image) {
@@ -618,27 +607,31 @@ func newRNAPolymerase(path string, rect Rectangle) RNAPolymerase {
 func (r *RNAPolymerase) update(params ...interface{}) {
 	if len(params) > 0 {
 		//g, ok := params[0].(*Game)
-		tfaPosY := transcriptionStruct.temp_tfa.rect.pos.y
+		tfaPosY := r.tfa.rect.pos.y
 		//if !ok {
 		//	return
 		//}
-		if tfaPosY >= 420 {
-			if r.rect.pos.x <= 80 {
-				r.rect.pos.y += 2 * (screenHeight / 750)
-				r.rect.pos.x += 4 * (screenWidth / 1250)
-			}
+		// Polymerase swings in behind the TF and clamps shut around the DNA
+		if tfaPosY >= 420 && !r.clamped {
+			r.clamped = true
+			tweens.add(tweenVector(&r.rect.pos, newVector(84, 342), clampTime, easeOutBack))
 		}
 		// Checks if current DNA codon is complete
 		if r.next {
-			if currentFrag == 5 && r.rect.pos.x < screenWidth+50 {
-				r.rect.pos.x += 5 * (screenWidth / 1250)
-				r.rect.pos.y += 3 * (screenHeight / 750)
-			} else if r.rect.pos.x < (160 * (currentFrag + 1)) {
-				r.rect.pos.x += 5 * (screenWidth / 1250)
-			} else {
-				transcriptionStruct.DNA[currentFrag].is_complete = false
-				reset = true
-				r.next = false
+			if pathwaySim.Fragment == 5 {
+				if r.rect.pos.x < screenWidth+50 {
+					r.rect.pos.move(polymeraseSpeed, descendSpeed)
+				} else {
+					r.onCodon()
+					r.next = false
+				}
+			} else if !r.moving {
+				r.moving = true
+				to := newVector(max(r.rect.pos.x, float64(160*(pathwaySim.Fragment+1))), r.rect.pos.y)
+				tweens.add(tweenVector(&r.rect.pos, to, translocateTime, easeInOutCubic).then(func() {
+					r.onCodon()
+					r.next, r.moving = false, false
+				}))
 			}
 		}
 	}
@@ -663,12 +656,11 @@ func (transcr Transcript) draw(screen *ebiten.Image) {
 
 func (transcr *Transcript) update(params ...interface{}) {
 	if transcr.isRNA {
-		if currentFrag < 5 {
-			transcr.rect.pos.x = transcriptionStruct.rnaPolymerase.rect.pos.x - 750
-		} else if transcriptionStruct.rnaPolymerase.rect.pos.x > 1000 {
+		if pathwaySim.Fragment < 5 {
+			transcr.rect.pos.x = transcr.polymerase.rect.pos.x - 750
+		} else if transcr.polymerase.rect.pos.x > 1000 {
 			if transcr.rect.pos.y > -600 {
-				transcr.rect.pos.y -= 4
-				transcr.rect.pos.x += 2
+				transcr.rect.pos.move(120, -240)
 			}
 		}
 	}
@@ -688,72 +680,24 @@ func (temp Template) draw(screen *ebiten.Image) {
 	temp.Sprite.draw(screen)
 }
 
-func (temp Template) update(params ...interface{}) {}
-
-func nextDNACodon() {
-	if currentFrag < 5 {
-		currentFrag++
-		transcriptionStruct.rnaPolymerase.next = true
-	}
-}
-
-func nextMRNACodon(g *Game) {
-	if mrna_ptr < 4 {
-		mrna_ptr++
-		mrna[mrna_ptr].is_complete = false
-		reset = true
-	} else {
-		ToMenu(g)
-		reset = false
-	}
-}
-
 func newCodonChoice(path string, rect Rectangle, codon string) CodonChoice {
 	sprite := newSprite(path, rect, 0.5)
 	var bases [3]Nucleobase
 	for x := 0; x < len(codon); x++ {
-		bases[x] = newNucleobase(string(codon[x]), newRect(8+sprite.rect.pos.x+(50*x), sprite.rect.pos.y+500, 65, 150), 0, false)
+		bases[x] = newNucleobase(string(codon[x]), newRect(8+sprite.rect.pos.x+float64(50*x), sprite.rect.pos.y+500, 65, 150), 0, false)
 	}
 	return CodonChoice{
-		Sprite:     sprite,
-		codon:      codon,
-		is_dragged: false,
-		bases:      bases,
+		Sprite:  sprite,
+		Dragger: Dragger{origin: sprite.rect.pos},
+		codon:   codon,
+		bases:   bases,
 	}
 }
 
+// Keeps the base images in step with the codon. The choice itself is moved by the level's drag and drop.
 func (c *CodonChoice) update(params ...interface{}) {
-	frag := params[0].(*Template)
-	var x_c, y_c = ebiten.CursorPosition()
-	var b_pos = newVector(x_c, y_c)
-	if rect_point_collision(c.Sprite.rect, b_pos) {
-		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-			c.is_dragged = true
-		} else if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
-			c.is_dragged = false
-			if len(params) == 2 {
-				if aabb_collision(c.rect, translationStruct.ribosome.rect) && c.codon == transcribe(frag.codon) {
-					frag.is_complete = true
-				}
-			} else if len(params) == 1 {
-				if aabb_collision(c.rect, transcriptionStruct.rnaPolymerase.rect) && c.codon == transcribe(frag.codon) {
-					frag.is_complete = true
-				}
-			}
-		}
-	}
-	if c.is_dragged {
-		c.Sprite.drag(true, true, b_pos)
-	}
 	for x := 0; x < len(c.bases); x++ {
 		c.bases[x].baseType = string(c.codon[x])
-		if len(params) == 2 {
-			c.bases[x].rect.pos.x = 65 + c.Sprite.rect.pos.x + (50 * x)
-			c.bases[x].rect.pos.y = c.Sprite.rect.pos.y + 300
-		} else {
-			c.bases[x].rect.pos.x = 85 + c.Sprite.rect.pos.x + (50 * x)
-			c.bases[x].rect.pos.y = c.Sprite.rect.pos.y + 125
-		}
 		switch c.bases[x].baseType {
 		case "A":
 		c.bases[x].Sprite.image = adenine.image
@@ -769,20 +713,28 @@ func (c *CodonChoice) update(params ...interface{}) {
 	}
 }
 
+// Bases are drawn by the scene graph, riding on the choice
 func (c CodonChoice) draw(screen *ebiten.Image) {
 	c.Sprite.draw(screen)
-	for _, base := range c.bases {
-		base.draw(screen)
+}
+
+// Adds the choice to a scene with its bases attached to it
+func (c *CodonChoice) addTo(parent *Node, z int) *Node {
+	node := parent.add(c, z)
+	for x := range c.bases {
+		node.attach(&c.bases[x], newVector(float64(85+50*x), 125), z)
 	}
+	return node
 }
 
-func (c *CodonChoice) reset(index, y_pos int, newBases string) {
-	c.rect.pos = newVector(spots[index], y_pos)
+func (c *CodonChoice) reset(x_pos float64, y_pos float64, newBases string) {
+	c.origin = newVector(x_pos, y_pos)
+	sendHome(c)
 	c.codon = newBases
 }
 
 func newTRNA(path string, rect Rectangle, codon string, amino string) tRNA {
-	codonChoice := newCodonChoice(path, rect, transcribe(codon))
+	codonChoice := newCodonChoice(path, rect, sim.Transcribe(codon))
 	aminoAcid := newNucleobase(amino, codonChoice.rect, 1, true)
 	return tRNA{
 		CodonChoice: codonChoice,
@@ -790,20 +742,18 @@ func newTRNA(path string, rect Rectangle, codon string, amino string) tRNA {
 	}
 }
 
-func (t *tRNA) update(params ...interface{}) {
-	t.CodonChoice.update(params[0], true)
-	t.aminoAcid.rect.pos.x = t.rect.pos.x+25
-	t.aminoAcid.rect.pos.y = t.rect.pos.y-25
-}
-
-func (t tRNA) draw(screen *ebiten.Image) {
-	t.CodonChoice.draw(screen)
-	t.aminoAcid.draw(screen)
+// Adds the tRNA to a scene with its anticodon bases and amino acid attached to it
+func (t *tRNA) addTo(parent *Node, z int) *Node {
+	node := parent.add(t, z)
+	for x := range t.bases {
+		node.attach(&t.bases[x], newVector(float64(65+50*x), 300), z)
+	}
+	node.attach(&t.aminoAcid, newVector(25, -25), z)
+	return node
 }
 
-func (t *tRNA) reset(index, y_pos int, newBases string, newAminoAcid string) {
-	t.rect.pos = newVector(spots[index], y_pos)
-	t.codon = transcribe(newBases)
+func (t *tRNA) reset(x_pos float64, y_pos float64, newBases string, newAminoAcid string) {
+	t.CodonChoice.reset(x_pos, y_pos, sim.Transcribe(newBases))
 	t.aminoAcid.baseType = newAminoAcid
 	if t.aminoAcid.baseType == "STOP" {
 		t.aminoAcid.Sprite.image = stop.image 
@@ -826,19 +776,29 @@ func (ribo *Ribosome) update(params ...interface{}) {
 		if !ok {
 			return
 		}
-		if ribo.rect.pos.x <= 40 {
-			ribo.rect.pos.y += 2 * (screenHeight / 750)
-			ribo.rect.pos.x += 4 * (screenWidth / 1250)
+		// Ribosome closes around the start codon, however often it comes back to it
+		if ribo.rect.pos.x <= 40 && !ribo.docking {
+			ribo.docking = true
+			to := newVector(44, ribo.rect.pos.y+120)
+			tweens.add(tweenVector(&ribo.rect.pos, to, clampTime/2, easeOutBack).then(func() {
+				ribo.docking = false
+			}))
 		}
-		// Checks if current mRNA codon is complete
-		if mrna[mrna_ptr].is_complete {
-			if mrna_ptr == 4 && ribo.rect.pos.x < screenWidth+50 {
-				ribo.rect.pos.x += 5 * (screenWidth / 1250)
-				ribo.rect.pos.y += 3 * (screenHeight / 750)
-			} else if ribo.rect.pos.x < (160 * (mrna_ptr + 1)) {
-				ribo.rect.pos.x += 5 * (screenWidth / 1250)
-			} else {
-				nextMRNACodon(g)
+		// Checks if current mRNA codon is complete; the ribosome lets go at the first stop codon
+		if pathwaySim.CodonComplete {
+			if pathwaySim.Codon == pathwaySim.StopCodon() {
+				if ribo.rect.pos.x < screenWidth+50 {
+					ribo.rect.pos.move(polymeraseSpeed, descendSpeed)
+				} else {
+					ribo.onCodon(g)
+				}
+			} else if !ribo.moving && !ribo.docking {
+				ribo.moving = true
+				to := newVector(max(ribo.rect.pos.x, float64(160*(pathwaySim.Codon+1))), ribo.rect.pos.y)
+				tweens.add(tweenVector(&ribo.rect.pos, to, translocateTime, easeInOutCubic).then(func() {
+					ribo.moving = false
+					ribo.onCodon(g)
+				}))
 			}
 		}
 	}
@@ -848,6 +808,37 @@ func (ribo Ribosome) draw(screen *ebiten.Image) {
 	ribo.Sprite.draw(screen)
 }
 
+func newEnzyme(path string, rect Rectangle, name string) Enzyme {
+	sprite := newSprite(path, rect, 0.4)
+	return Enzyme{
+		Sprite:        sprite,
+		name:          name,
+		is_clicked_on: false,
+	}
+}
+
+// Enzyme drawn at runtime rather than from an image file, with its rect sized to the drawing
+func newDrawnEnzyme(rect Rectangle, name string) Enzyme {
+	img := enzymeImage(name)
+	rect.width, rect.height = float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	return Enzyme{
+		Sprite: Sprite{image: img, image_2: img, rect: rect, scaleW: 1, scaleH: 1},
+		name:   name,
+	}
+}
+
+func (e *Enzyme) update(params ...interface{}) {
+	var b_pos = cursorVector()
+	if rect_point_collision(e.rect, b_pos) && input.isJustPressed() {
+		e.is_clicked_on = true
+	}
+}
+
+func (e Enzyme) draw(screen *ebiten.Image) {
+	e.Sprite.draw(screen)
+	defaultFont.drawFont(screen, e.name, e.rect.pos.x+25, e.rect.pos.y+60, color.RGBA{50, 0, 50, 250})
+}
+
 func newNucleobase(btype string, rect Rectangle, index int, isTemp bool) Nucleobase {
 	var path string
 	if btype != "A" && btype != "T" && btype != "G" && btype != "C" && btype != "U" && btype != "N/A" && btype != "STOP" {
@@ -879,7 +870,9 @@ var nucleobaseImages = map[string]string{
 func (n Nucleobase) draw(screen *ebiten.Image) {
 	n.Sprite.draw(screen)
 	if !n.isTemplate {
-		if n.baseType != "N/A" {
+		if n.is_mismatch {
+			codonFont.drawFont(screen, n.baseType, n.rect.pos.x-50, n.rect.pos.y-50, color.RGBA{200, 0, 0, 255})
+		} else if n.baseType != "N/A" {
 			codonFont.drawFont(screen, n.baseType, n.rect.pos.x-50, n.rect.pos.y-50, color.Black)
 		}
 	} else {
@@ -890,9 +883,29 @@ func (n Nucleobase) draw(screen *ebiten.Image) {
 
 }
 
-func (n *Nucleobase) update(params ...interface{}) {
-	n.rect.pos.x = (675 + transcriptionStruct.RNA[currentFrag].rect.pos.x + (50 * n.index)) - 150*(currentFrag-1)
-	n.rect.pos.y = (transcriptionStruct.RNA[5].rect.pos.y + 400 + (25 * n.index)) - 75*(currentFrag-1)
+// Swaps the base type and image of a nucleobase, e.g. for a misincorporated or corrected base
+func (n *Nucleobase) setBase(btype string) {
+	n.baseType = btype
+	switch btype {
+	case "A":
+		n.Sprite.image = adenine.image
+	case "T":
+		n.Sprite.image = thymine.image
+	case "G":
+		n.Sprite.image = guanine.image
+	case "C":
+		n.Sprite.image = cytosine.image
+	case "U":
+		n.Sprite.image = uracil.image
+	}
+}
+
+// Non-template bases are rotated 180 degrees, so they are drawn up and to the left of rect.pos
+func (n Nucleobase) hitbox() Rectangle {
+	if n.isTemplate {
+		return n.rect
+	}
+	return newRect(n.rect.pos.x-n.rect.width, n.rect.pos.y-n.rect.height, n.rect.width, n.rect.height)
 }
 
 func (n Nucleobase) String() string {
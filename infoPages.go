@@ -1,6 +1,7 @@
 package main
 
-func updateInfo() string {
+// Info page text for a scene
+func updateInfo(scene string) string {
 	switch scene {
 	case "Signal Reception":
 		info = "WELCOME TO THE SIGNAL\nRECEPTION STAGE!\n" +
@@ -19,13 +20,44 @@ func updateInfo() string {
 		"The activated TFA enters the nucleus\nand binds to the DNA" +
 		" template strand,\nallowing RNA polymerase to bind\nto the template.\n" +
 		"RNA polymerase then 'reads' the\ntemplate strand from 3' to 5',\n" +
-		"synthesizing a new mRNA molecule with\ncomplementary bases from 5' to 3'."
+		"synthesizing a new mRNA molecule with\ncomplementary bases from 5' to 3'.\n" +
+		"Closed chromatin or a methylated\npromoter hides the gene: HATs open it,\n" +
+		"HDACs close it, demethylases unmark it."
 	case "Translation":
 		info = "WELCOME TO THE PROTEIN\nTRANSLATION STAGE!\n" +
 		"The complete mRNA molecule exits the\nnucleus and travels to the\n" +
 		"cytoplasm, where a ribosome finds the 5'\nguanosine cap and scans for\n" +
 		"the first start codon. The ribosome then\nforms peptide bonds between\n" +
-		"amino acids from tRNA with complementary\ncodons to the mRNA transcript."
+		"amino acids from tRNA with complementary\ncodons to the mRNA transcript.\n" +
+		"Many ribosomes can read one mRNA until\nits poly-A tail shortens and it decays;\n" +
+		"microRNAs pairing with the 3' UTR\nblock translation and speed decay."
+	case "DNA Replication":
+		info = "WELCOME TO THE DNA\nREPLICATION STAGE!\n" +
+		"Helicase unwinds the double helix and\nprimase lays down RNA primers." +
+		" DNA\npolymerase adds complementary bases\nonly from 5' to 3', so the" +
+		" leading\nstrand is copied continuously and the\nlagging strand in" +
+		" Okazaki fragments\nthat DNA ligase seals together."
+	case "Nuclear Import":
+		info = "WELCOME TO THE NUCLEAR\nENVELOPE!\n" +
+		"Large proteins can only enter the\nnucleus through nuclear pores." +
+		" Importin\nbinds cargo with a nuclear localization\nsignal (NLS)" +
+		" and carries it through.\nRan-GTP, high inside the nucleus,\nbinds importin" +
+		" and releases the cargo."
+	case "Nuclear Export":
+		info = "WELCOME TO NUCLEAR EXPORT!\n" +
+		"Exportin binds Ran-GTP together with\ncargo carrying a nuclear export\n" +
+		"signal (NES), like processed mRNA.\nIn the cytoplasm Ran-GTP is hydrolysed" +
+		"\nto Ran-GDP and the cargo is released.\nDNA and RNA polymerase stay behind."
+	case "Quorum Sensing":
+		info = "WELCOME TO THE QUORUM\nSENSING STAGE!\n" +
+		"Bacteria secrete autoinducers that\nbuild up as the population grows." +
+		"\nAt quorum, a sensor histidine kinase\nautophosphorylates and passes the" +
+		"\nphosphate to a response regulator:\na two-component system."
+	case "Coupled Expression":
+		info = "WELCOME TO COUPLED\nTRANSCRIPTION-TRANSLATION!\n" +
+		"Bacteria have no nucleus, so ribosomes\nbind Shine-Dalgarno sites on mRNA" +
+		"\nthat RNA polymerase is still making.\nA sigma factor picks the promoter," +
+		"\nand one operon mRNA can carry several\ngenes, each with its own start codon."
 	default:
 		info = ""
 	}
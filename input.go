@@ -0,0 +1,426 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Where sprites read the pointer and keys from. Every backend reports a single pointer
+// (mouse, first touch or a virtual cursor) so levels do not care what the player is holding.
+// update is called once per fixed step, before the step reads anything.
+type InputSource interface {
+	update()
+	cursorPosition() (int, int)
+	isPressed() bool
+	isJustPressed() bool
+	isJustReleased() bool
+	isKeyJustPressed(key ebiten.Key) bool
+}
+
+// Input every sprite reads from; swap it for a ScriptedInput to drive the game without a player
+var input InputSource = newLiveInput()
+
+// A source read from devices, which must be sampled once every Ebiten tick. A tick can run
+// several fixed steps or none, so presses and releases are kept until a step takes them.
+type PolledInput interface {
+	poll()
+}
+
+// Units per second a virtual cursor moves when steered with arrow keys or a thumbstick
+const virtualCursorSpeed = 480
+
+// How far a virtual cursor moves in one Ebiten tick, whatever the tick rate
+func virtualCursorStep() float64 {
+	return virtualCursorSpeed / float64(ebiten.TPS())
+}
+
+// Cursor position as a vector, for collision checks against sprite rectangles
+func cursorVector() Vector {
+	x_c, y_c := input.cursorPosition()
+	return newVector(float64(x_c), float64(y_c))
+}
+
+func clampToScreen(x, y float64) (float64, float64) {
+	x = max(0, min(x, screenWidth))
+	y = max(0, min(y, screenHeight))
+	return x, y
+}
+
+// MOUSE
+type MouseInput struct{}
+
+func (m *MouseInput) update() {}
+
+func (m *MouseInput) cursorPosition() (int, int) {
+	return camera.toLogical(ebiten.CursorPosition())
+}
+
+func (m *MouseInput) isPressed() bool {
+	return ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+}
+
+func (m *MouseInput) isJustPressed() bool {
+	return inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+}
+
+func (m *MouseInput) isJustReleased() bool {
+	return inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft)
+}
+
+func (m *MouseInput) isKeyJustPressed(key ebiten.Key) bool {
+	return false
+}
+
+// KEYBOARD: arrow keys steer a virtual cursor and Space or Enter acts as the mouse button
+type KeyboardInput struct {
+	x, y float64
+}
+
+func (k *KeyboardInput) update() {
+	speed := virtualCursorStep()
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		k.x -= speed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		k.x += speed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		k.y -= speed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		k.y += speed
+	}
+	k.x, k.y = clampToScreen(k.x, k.y)
+}
+
+func (k *KeyboardInput) cursorPosition() (int, int) {
+	return int(k.x), int(k.y)
+}
+
+func (k *KeyboardInput) isPressed() bool {
+	return ebiten.IsKeyPressed(ebiten.KeySpace) || ebiten.IsKeyPressed(ebiten.KeyEnter)
+}
+
+func (k *KeyboardInput) isJustPressed() bool {
+	return inpututil.IsKeyJustPressed(ebiten.KeySpace) || inpututil.IsKeyJustPressed(ebiten.KeyEnter)
+}
+
+func (k *KeyboardInput) isJustReleased() bool {
+	return inpututil.IsKeyJustReleased(ebiten.KeySpace) || inpututil.IsKeyJustReleased(ebiten.KeyEnter)
+}
+
+func (k *KeyboardInput) isKeyJustPressed(key ebiten.Key) bool {
+	return inpututil.IsKeyJustPressed(key)
+}
+
+// TOUCH: follows the first finger down until it lifts
+type TouchInput struct {
+	id       ebiten.TouchID
+	touching bool
+	released bool
+	x, y     int
+	ids      []ebiten.TouchID
+}
+
+func (t *TouchInput) update() {
+	t.released = false
+	if t.touching {
+		if inpututil.IsTouchJustReleased(t.id) {
+			// Keep the last position so the drop lands where the finger lifted
+			t.touching = false
+			t.released = true
+		} else {
+			t.x, t.y = camera.toLogical(ebiten.TouchPosition(t.id))
+		}
+		return
+	}
+	t.ids = inpututil.AppendJustPressedTouchIDs(t.ids[:0])
+	if len(t.ids) > 0 {
+		t.id = t.ids[0]
+		t.touching = true
+		t.x, t.y = camera.toLogical(ebiten.TouchPosition(t.id))
+	}
+}
+
+func (t *TouchInput) cursorPosition() (int, int) {
+	return t.x, t.y
+}
+
+func (t *TouchInput) isPressed() bool {
+	return t.touching
+}
+
+func (t *TouchInput) isJustPressed() bool {
+	return t.touching && inpututil.TouchPressDuration(t.id) == 1
+}
+
+func (t *TouchInput) isJustReleased() bool {
+	return t.released
+}
+
+func (t *TouchInput) isKeyJustPressed(key ebiten.Key) bool {
+	return false
+}
+
+// GAMEPAD: the left stick or d-pad steers a virtual cursor and the bottom face button
+// (A on Xbox, Cross on PlayStation) acts as the mouse button
+type GamepadInput struct {
+	id   ebiten.GamepadID
+	ok   bool
+	x, y float64
+	ids  []ebiten.GamepadID
+}
+
+const gamepadDeadZone = 0.2
+
+func (p *GamepadInput) update() {
+	p.ids = ebiten.AppendGamepadIDs(p.ids[:0])
+	p.ok = false
+	for _, id := range p.ids {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			p.id, p.ok = id, true
+			break
+		}
+	}
+	if !p.ok {
+		return
+	}
+	speed := virtualCursorStep()
+	dx := ebiten.StandardGamepadAxisValue(p.id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+	dy := ebiten.StandardGamepadAxisValue(p.id, ebiten.StandardGamepadAxisLeftStickVertical)
+	if dx > gamepadDeadZone || dx < -gamepadDeadZone {
+		p.x += dx * speed
+	}
+	if dy > gamepadDeadZone || dy < -gamepadDeadZone {
+		p.y += dy * speed
+	}
+	if ebiten.IsStandardGamepadButtonPressed(p.id, ebiten.StandardGamepadButtonLeftLeft) {
+		p.x -= speed
+	}
+	if ebiten.IsStandardGamepadButtonPressed(p.id, ebiten.StandardGamepadButtonLeftRight) {
+		p.x += speed
+	}
+	if ebiten.IsStandardGamepadButtonPressed(p.id, ebiten.StandardGamepadButtonLeftTop) {
+		p.y -= speed
+	}
+	if ebiten.IsStandardGamepadButtonPressed(p.id, ebiten.StandardGamepadButtonLeftBottom) {
+		p.y += speed
+	}
+	p.x, p.y = clampToScreen(p.x, p.y)
+}
+
+func (p *GamepadInput) cursorPosition() (int, int) {
+	return int(p.x), int(p.y)
+}
+
+func (p *GamepadInput) isPressed() bool {
+	return p.ok && ebiten.IsStandardGamepadButtonPressed(p.id, ebiten.StandardGamepadButtonRightBottom)
+}
+
+func (p *GamepadInput) isJustPressed() bool {
+	return p.ok && inpututil.IsStandardGamepadButtonJustPressed(p.id, ebiten.StandardGamepadButtonRightBottom)
+}
+
+func (p *GamepadInput) isJustReleased() bool {
+	return p.ok && inpututil.IsStandardGamepadButtonJustReleased(p.id, ebiten.StandardGamepadButtonRightBottom)
+}
+
+func (p *GamepadInput) isKeyJustPressed(key ebiten.Key) bool {
+	return false
+}
+
+// LIVE: reads every device at once and follows whichever one the player used last.
+// Devices are polled every Ebiten tick; presses, releases and keys are latched until the
+// next fixed step takes them, so none is lost to a tick that runs no step.
+type LiveInput struct {
+	mouse    MouseInput
+	keyboard KeyboardInput
+	touch    TouchInput
+	gamepad  GamepadInput
+	current  InputSource
+	lastX    [4]int
+	lastY    [4]int
+
+	latched InputEdges   // Seen since the last step
+	edges   InputEdges   // Seen by the current step
+	keys    []ebiten.Key // Keys pressed this tick, reused from poll to poll
+}
+
+// Presses, releases and keys seen over some ticks
+type InputEdges struct {
+	pressed  bool
+	released bool
+	keys     []ebiten.Key
+}
+
+func newLiveInput() *LiveInput {
+	l := &LiveInput{}
+	l.current = &l.mouse
+	return l
+}
+
+func (l *LiveInput) sources() [4]InputSource {
+	return [4]InputSource{&l.mouse, &l.keyboard, &l.touch, &l.gamepad}
+}
+
+func (l *LiveInput) poll() {
+	for i, source := range l.sources() {
+		source.update()
+		x, y := source.cursorPosition()
+		moved := x != l.lastX[i] || y != l.lastY[i]
+		l.lastX[i], l.lastY[i] = x, y
+		if source == l.current || l.current.isPressed() {
+			continue
+		}
+		// Switching device mid-drag would drop whatever the player is holding
+		if moved || source.isJustPressed() {
+			if i == 1 || i == 3 {
+				// A virtual cursor starts from where the pointer was
+				cx, cy := l.current.cursorPosition()
+				if i == 1 {
+					l.keyboard.x, l.keyboard.y = float64(cx), float64(cy)
+				} else {
+					l.gamepad.x, l.gamepad.y = float64(cx), float64(cy)
+				}
+				l.lastX[i], l.lastY[i] = cx, cy
+			}
+			l.current = source
+		}
+	}
+	l.latched.pressed = l.latched.pressed || l.current.isJustPressed()
+	l.latched.released = l.latched.released || l.current.isJustReleased()
+	l.keys = inpututil.AppendJustPressedKeys(l.keys[:0])
+	l.latched.keys = append(l.latched.keys, l.keys...)
+}
+
+// Hands the step everything latched since the last one
+func (l *LiveInput) update() {
+	l.edges, l.latched = l.latched, InputEdges{keys: l.edges.keys[:0]}
+}
+
+func (l *LiveInput) cursorPosition() (int, int) {
+	return l.current.cursorPosition()
+}
+
+func (l *LiveInput) isPressed() bool {
+	return l.current.isPressed()
+}
+
+func (l *LiveInput) isJustPressed() bool {
+	return l.edges.pressed
+}
+
+func (l *LiveInput) isJustReleased() bool {
+	return l.edges.released
+}
+
+func (l *LiveInput) isKeyJustPressed(key ebiten.Key) bool {
+	for _, k := range l.edges.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the pointer is a virtual cursor that needs drawing, since there is no system cursor for it
+func (l *LiveInput) virtualCursor() bool {
+	return l.current == &l.keyboard || l.current == &l.gamepad
+}
+
+// One scripted change to the pointer or keys, applied on the given tick
+type InputEvent struct {
+	tick    int
+	x, y    int
+	pressed bool
+	keys    []ebiten.Key // Keys that go down on this event's tick
+	keyOnly bool         // Leaves the pointer where it is and the button as it is, for keys scripted on their own
+	draws   int          // Random draws made before this tick, only set in recorded sessions
+}
+
+// SCRIPTED: plays back a list of events tick by tick, e.g. a demo or a recorded session
+type ScriptedInput struct {
+	events  []InputEvent
+	next    int
+	tick    int
+	x, y    int
+	pressed bool
+	wasDown bool
+	keys    []ebiten.Key // Keys of every event on the current tick, cleared when the next tick starts
+}
+
+func newScriptedInput(events ...InputEvent) *ScriptedInput {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].tick < events[j].tick })
+	return &ScriptedInput{events: events}
+}
+
+func (s *ScriptedInput) update() {
+	s.wasDown = s.pressed
+	s.keys = s.keys[:0]
+	for s.next < len(s.events) && s.events[s.next].tick <= s.tick {
+		e := s.events[s.next]
+		if !e.keyOnly {
+			s.x, s.y, s.pressed = e.x, e.y, e.pressed
+		}
+		s.keys = append(s.keys, e.keys...)
+		s.next++
+	}
+	s.tick++
+}
+
+func (s *ScriptedInput) cursorPosition() (int, int) {
+	return s.x, s.y
+}
+
+func (s *ScriptedInput) isPressed() bool {
+	return s.pressed
+}
+
+func (s *ScriptedInput) isJustPressed() bool {
+	return s.pressed && !s.wasDown
+}
+
+func (s *ScriptedInput) isJustReleased() bool {
+	return !s.pressed && s.wasDown
+}
+
+func (s *ScriptedInput) isKeyJustPressed(key ebiten.Key) bool {
+	for _, k := range s.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if every event in the script has been played
+func (s *ScriptedInput) done() bool {
+	return s.next == len(s.events)
+}
+
+// Press and release at a point
+func scriptClick(tick int, x, y int) []InputEvent {
+	return []InputEvent{
+		{tick: tick, x: x, y: y, pressed: true},
+		{tick: tick + 1, x: x, y: y, pressed: false},
+	}
+}
+
+// Picks a sprite up at one point and drops it at another, moving over the given number of ticks
+func scriptDrag(tick int, from, to Vector, ticks int) []InputEvent {
+	ticks = max(ticks, 1)
+	events := []InputEvent{{tick: tick, x: int(from.x), y: int(from.y), pressed: true}}
+	for i := 1; i <= ticks; i++ {
+		x := from.x + (to.x-from.x)*float64(i)/float64(ticks)
+		y := from.y + (to.y-from.y)*float64(i)/float64(ticks)
+		events = append(events, InputEvent{tick: tick + i, x: int(x), y: int(y), pressed: true})
+	}
+	return append(events, InputEvent{tick: tick + ticks + 1, x: int(to.x), y: int(to.y), pressed: false})
+}
+
+// Presses a key wherever the pointer is, so a key pressed mid-drag keeps the molecule held
+func scriptKey(tick int, key ebiten.Key) []InputEvent {
+	return []InputEvent{{tick: tick, keys: []ebiten.Key{key}, keyOnly: true}}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Plays a script into a level's drag and drop until every event has been played
+func replay(dd *DragDrop, events ...InputEvent) *ScriptedInput {
+	script := newScriptedInput(events...)
+	input = script
+	for !script.done() {
+		input.update()
+		dd.update()
+	}
+	// One more tick lets the last event's release be seen
+	input.update()
+	dd.update()
+	return script
+}
+
+func TestScriptedDragOntoTarget(t *testing.T) {
+	defer func(live InputSource) { input = live }(input)
+
+	cargo := &Cargo{Sprite: Sprite{rect: newRect(100, 100, 50, 50)}, name: "TF"}
+	zone := newRect(600, 400, 100, 100)
+	var taken []Draggable
+	target := newTarget(&zone, func(d Draggable) bool {
+		taken = append(taken, d)
+		return true
+	}).snapAt(newVector(10, 10))
+	dd := newDragDrop([]Draggable{cargo}, target)
+
+	events := scriptDrag(0, newVector(125, 125), newVector(650, 450), 10)
+	// A key pressed halfway must not make the pointer let go of the molecule
+	events = append(events, scriptKey(5, ebiten.KeyQ)...)
+	replay(dd, events...)
+
+	if len(taken) != 1 || taken[0] != cargo {
+		t.Fatalf("target took %v, want the dragged cargo once", taken)
+	}
+	if !cargo.locked || cargo.rect.pos != newVector(610, 410) {
+		t.Fatalf("cargo at %v locked %v, want snapped to (610, 410)", cargo.rect.pos, cargo.locked)
+	}
+}
+
+func TestScriptedKeyKeepsPointer(t *testing.T) {
+	defer func(live InputSource) { input = live }(input)
+
+	script := newScriptedInput(append(
+		[]InputEvent{{tick: 0, x: 300, y: 200, pressed: true}},
+		scriptKey(1, ebiten.KeyE)...)...)
+	input = script
+	input.update()
+	input.update()
+	if x, y := input.cursorPosition(); x != 300 || y != 200 || !input.isPressed() {
+		t.Fatalf("pointer at (%d, %d) pressed %v after a key, want (300, 200) held", x, y, input.isPressed())
+	}
+	if !input.isKeyJustPressed(ebiten.KeyE) || input.isJustReleased() {
+		t.Fatal("scripted key was not seen, or released the button")
+	}
+}
+
+func TestScriptedClick(t *testing.T) {
+	defer func(live InputSource) { input = live }(input)
+
+	input = newScriptedInput(scriptClick(0, 40, 60)...)
+	input.update()
+	if !input.isJustPressed() {
+		t.Fatal("click did not press the button")
+	}
+	input.update()
+	if !input.isJustReleased() {
+		t.Fatal("click did not release the button on the next tick")
+	}
+}
+
+func TestLiveInputLatchesEdges(t *testing.T) {
+	l := newLiveInput()
+	// A press and a key seen on a tick that ran no step
+	l.latched = InputEdges{pressed: true, keys: []ebiten.Key{ebiten.KeyE}}
+	l.update()
+	if !l.isJustPressed() || !l.isKeyJustPressed(ebiten.KeyE) {
+		t.Fatal("step did not see the press and key latched before it")
+	}
+	l.update()
+	if l.isJustPressed() || l.isKeyJustPressed(ebiten.KeyE) {
+		t.Fatal("press and key were seen by a second step")
+	}
+}
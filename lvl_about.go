@@ -10,14 +10,13 @@ type About struct {
 	aboutBg          	StillImage
 	aboutToMenuButton 	Button
 	message				string
+	scene				*SceneGraph
 }
 
-var aboutStruct *About
-
-// Initialize about struct and aboutSprites array if not initialized, then set state to aboutStruct
+// Initialize about struct and aboutSprites array if not initialized, then set state to it
 func newAbout(g *Game) {
 	if len(g.aboutSprites ) == 0 {
-		aboutStruct = &About{
+		a := &About{
 			aboutBg: newStillImage("AboutBg.png", newRect(0, 0, 1250, 750)),
 			aboutToMenuButton: newButton("menuButton.png", newRect(350, 450, 300, 200), ToMenu),
 			message:	"WELCOME TO THE CELL\nSIGNALING PATHWAY\nSIMULATOR!\n" +
@@ -25,9 +24,17 @@ func newAbout(g *Game) {
 						"pathway from reception\nthrough translation!\nClick the play button\n" +
 						"or select a level\nto begin.",
 		}
-		g.aboutSprites = []GUI{&aboutStruct.aboutBg, &aboutStruct.aboutToMenuButton}
+		g.aboutSprites = []Updatable{&a.aboutToMenuButton}
+
+		a.scene = newSceneGraph()
+		a.scene.add(&a.aboutBg, zBackground)
+		a.scene.add(&a.aboutToMenuButton, zButtons)
+		a.scene.add(DrawFunc(func(screen *ebiten.Image) {
+			defaultFont.drawFont(screen, a.message, 775, 275, color.RGBA{50, 5, 5, 250})
+		}), zText)
+		g.levels["About"] = a
 	}
-	g.stateMachine.state = aboutStruct
+	g.stateMachine.state = g.levels["About"]
 }
 
 func (a *About) Init(g *Game) {
@@ -41,8 +48,5 @@ func (a *About) Update(g *Game) {
 }
 
 func (a *About) Draw(g *Game, screen *ebiten.Image) {
-	for _, element := range g.aboutSprites {
-		element.draw(screen)
-	}
-	defaultFont.drawFont(screen, a.message, 775, 275, color.RGBA{50, 5, 5, 250})
+	a.scene.draw(screen)
 }
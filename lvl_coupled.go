@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type CoupledLevel struct {
+	// COUPLED TRANSCRIPTION-TRANSLATION SPRITES
+	cytoBg            StillImage
+	dnaStrand         StillImage
+	rnaPolymerase     Enzyme
+	sigma70           Enzyme
+	sigma32           Enzyme
+	ribosomes         [2]Ribosome
+	infoButton        InfoPage
+	otherToMenuButton Button
+	message           string
+	scene             *SceneGraph
+	sigmaNode         *Node
+	ribosomeNodes     [2]*Node
+
+	doneTimer float64
+}
+
+// Operon segments are laid out left to right, each this many pixels wide
+const segmentWidth = 130
+
+// Seconds the expressed operon stays on screen before going back to the menu
+const coupledDoneTime = 3.0
+
+func newCoupledLevel(g *Game) {
+	if len(g.coupledSprites) == 0 {
+		c := &CoupledLevel{
+			cytoBg:    newStillImage("CytoBg2.png", newRect(0, 0, 1250, 750)),
+			dnaStrand: newStillImage("DNA.png", newRect(0, 450, 1250, 262)),
+
+			rnaPolymerase: newEnzyme("codonButton.png", newRect(-100, 380, 192, 106), "RNA Pol"),
+			sigma70:       newEnzyme("codonButton.png", newRect(650, 200, 192, 106), "Sigma70"),
+			sigma32:       newEnzyme("codonButton.png", newRect(900, 200, 192, 106), "Sigma32"),
+			message: "WELCOME TO THE BACTERIAL CYTOPLASM! \n" +
+				"Give RNA polymerase the right sigma \n" +
+				"factor, then click Shine-Dalgarno \n" +
+				"sites to load ribosomes on the mRNA!",
+		}
+		for x := range c.ribosomes {
+			c.ribosomes[x] = Ribosome{Sprite: newSprite("ribosome.png", newRect(0, 200, 155, 167), 0.25)}
+		}
+		c.infoButton = infoButton
+		c.otherToMenuButton = otherToMenuButton
+
+		g.coupledSprites = []Updatable{
+			&c.rnaPolymerase, &c.sigma70, &c.sigma32, &c.ribosomes[0],
+			&c.ribosomes[1], &c.otherToMenuButton, &c.infoButton,
+		}
+
+		c.scene = newSceneGraph()
+		c.scene.add(&c.cytoBg, zBackground)
+		c.scene.add(&c.dnaStrand, zBackground)
+		c.scene.add(DrawFunc(c.DrawTranscript), zWorld)
+		c.scene.add(&c.rnaPolymerase, zWorld)
+		for x := range c.ribosomes {
+			c.ribosomeNodes[x] = c.scene.add(&c.ribosomes[x], zWorld)
+		}
+		c.sigmaNode = c.scene.root.group(zWorld)
+		c.sigmaNode.add(&c.sigma70, zWorld)
+		c.sigmaNode.add(&c.sigma32, zWorld)
+		c.scene.add(DrawFunc(c.DrawText), zText)
+		c.scene.add(&c.otherToMenuButton, zButtons)
+		c.scene.add(&c.infoButton, zOverlay)
+		g.levels["Coupled Expression"] = c
+	}
+	g.stateMachine.state = g.levels["Coupled Expression"]
+}
+
+func (c *CoupledLevel) Init(g *Game) {
+	c.doneTimer = 0
+	c.rnaPolymerase.rect.pos.x = -100
+	g.state_array = g.coupledSprites
+}
+
+func (c *CoupledLevel) Update(g *Game) {
+	c.otherToMenuButton.update(g)
+	c.infoButton.update()
+	o := &pathwaySim.Operon
+
+	switch o.Phase {
+	case sim.SigmaBinding:
+		c.sigma70.update()
+		c.sigma32.update()
+		if c.sigma70.is_clicked_on || c.sigma32.is_clicked_on {
+			chosen := sim.Promoters[0]
+			if c.sigma32.is_clicked_on {
+				chosen = sim.Promoters[1]
+			}
+			c.sigma70.is_clicked_on, c.sigma32.is_clicked_on = false, false
+			pathwaySim.Step(sim.Input{Action: sim.ChooseSigma, Target: chosen})
+		}
+	case sim.Transcribing:
+		c.rnaPolymerase.rect.pos.x = float64(100 + (segmentWidth * o.Transcribed) - 40)
+	case sim.Terminated:
+		if c.rnaPolymerase.rect.pos.x < screenWidth+50 {
+			c.rnaPolymerase.rect.pos.move(polymeraseSpeed, 0)
+		}
+	case sim.Translated:
+		c.doneTimer += fixedStep
+		if c.doneTimer > coupledDoneTime {
+			ToMenu(g)
+			return
+		}
+	}
+
+	var b_pos = cursorVector()
+	for x := range c.ribosomes {
+		sd := o.FirstSegment(x)
+		if rect_point_collision(newRect(float64(100+(segmentWidth*sd)), 300, segmentWidth, 80), b_pos) && input.isJustPressed() {
+			pathwaySim.Step(sim.Input{Action: sim.LoadRibosome, Index: x})
+		}
+	}
+	pathwaySim.Step(sim.Input{Action: sim.Express})
+	for x := range c.ribosomes {
+		c.ribosomes[x].rect.pos.x = float64(100 + (segmentWidth * o.Ribosomes[x].Segment) - 10)
+		c.ribosomes[x].rect.pos.y = 200
+	}
+}
+
+func (c *CoupledLevel) Draw(g *Game, screen *ebiten.Image) {
+	for x, node := range c.ribosomeNodes {
+		node.visible = pathwaySim.Operon.Ribosomes[x].Loaded
+	}
+	c.sigmaNode.visible = pathwaySim.Operon.Phase == sim.SigmaBinding
+	c.scene.draw(screen)
+}
+
+// Nascent mRNA, only as far as RNA polymerase has transcribed
+func (c *CoupledLevel) DrawTranscript(screen *ebiten.Image) {
+	o := &pathwaySim.Operon
+	for x := 0; x < o.Transcribed && x < len(o.Segments); x++ {
+		seg := o.Segments[x]
+		clr := color.Color(color.Black)
+		if seg.Kind == "SD" {
+			clr = color.RGBA{200, 0, 0, 255}
+		}
+		defaultFont.drawFont(screen, seg.Codon, float64(100+(segmentWidth*x)), 350, clr)
+	}
+}
+
+func (c *CoupledLevel) DrawText(screen *ebiten.Image) {
+	o := &pathwaySim.Operon
+	switch o.Phase {
+	case sim.SigmaBinding:
+		defaultFont.drawFont(screen, "Promoter: "+o.Promoter, 75, 200, color.Black)
+		if o.WrongSigma {
+			defaultFont.drawFont(screen, "That sigma factor does not\nrecognise this promoter!", 75, 250, color.RGBA{200, 0, 0, 255})
+		}
+	case sim.Translated:
+		defaultFont.drawFont(screen, "OPERON EXPRESSED!", 75, 200, color.Black)
+	}
+
+	for x, r := range o.Ribosomes {
+		if len(r.Protein) > 0 {
+			defaultFont.drawFont(screen, "Protein "+fmt.Sprint(x+1)+": "+strings.Join(r.Protein, "-"), 75, float64(600+(40*x)), color.Black)
+		}
+	}
+
+	defaultFont.drawFont(screen, c.message, 75, 50, color.Black)
+}
@@ -17,32 +17,46 @@ type MainMenu struct {
 	aboutButton  Button
 	levSelButton Button
 	volButton    VolButton
+	scene        *SceneGraph
 }
 
-var menuStruct *MainMenu
-
-// Initialize menu struct and menuSprites array if not initialized, then set state to menuStruct
+// Initialize menu struct and menuSprites array if not initialized, then set state to it
 func newMainMenu(g *Game) {
+	if audioPlayer == nil {
+		startMusic()
+	}
 	if len(g.menuSprites) == 0 {
-		menuStruct = &MainMenu{
+		m := &MainMenu{
 			protoStartBg: newStillImage("MenuBg.png", newRect(0, 0, 1250, 750)),
-			startBg:      newParallax("StartBg.png", newRect(0, 0, 1250, 750), 5),
-			startP1:      newParallax("parallax-Start2.png", newRect(0, 0, 1250, 750), 4),
-			startP2:      newParallax("parallax-Start3.png", newRect(0, 0, 1250, 750), 3),
-			startP3:      newParallax("parallax-Start4.png", newRect(0, 0, 1250, 750), 2),
-			startP4:      newParallax("parallax-Start5.png", newRect(0, 0, 1250, 750), 1),
+			startBg:      newParallax("Main Menu", "StartBg.png", newRect(0, 0, 1250, 750), 5),
+			startP1:      newParallax("Main Menu", "parallax-Start2.png", newRect(0, 0, 1250, 750), 4),
+			startP2:      newParallax("Main Menu", "parallax-Start3.png", newRect(0, 0, 1250, 750), 3),
+			startP3:      newParallax("Main Menu", "parallax-Start4.png", newRect(0, 0, 1250, 750), 2),
+			startP4:      newParallax("Main Menu", "parallax-Start5.png", newRect(0, 0, 1250, 750), 1),
 			fixedStart:   newStillImage("fixed-Start.png", newRect(0, 0, 1250, 750)),
 			playbutton:   newButton("PlayButton.png", newRect(750, 100, 300, 200), ToPlasma),
 			aboutButton:  newButton("aboutButton.png", newRect(770, 260, 300, 200), ToAbout),
 			levSelButton: newButton("levSelButton.png", newRect(700, 450, 300, 200), ToLevelSelect),
 		}
-		menuStruct.volButton = newVolButton("volButtonOn.png", newRect(100, 100, 165, 165), menuStruct.volButton.Toggle, *audioPlayer)
-		g.menuSprites = []GUI{
-			&menuStruct.protoStartBg, &menuStruct.startBg, &menuStruct.startP1, &menuStruct.startP2,
-			&menuStruct.startP3, &menuStruct.startP4, &menuStruct.fixedStart, &menuStruct.playbutton,
-			&menuStruct.aboutButton, &menuStruct.levSelButton, &menuStruct.volButton}
+		m.volButton = newVolButton("volButtonOn.png", newRect(100, 100, 165, 165), m.volButton.Toggle, *audioPlayer)
+		g.menuSprites = []Updatable{
+			&m.startBg, &m.startP1, &m.startP2, &m.startP3,
+			&m.startP4, &m.playbutton, &m.aboutButton,
+			&m.levSelButton, &m.volButton}
+
+		m.scene = newSceneGraph()
+		m.scene.add(&m.protoStartBg, zBackground)
+		for _, layer := range []*Parallax{&m.startBg, &m.startP1, &m.startP2, &m.startP3, &m.startP4} {
+			m.scene.add(layer, zBackground)
+		}
+		m.scene.add(&m.fixedStart, zBackground)
+		m.scene.add(&m.playbutton, zButtons)
+		m.scene.add(&m.aboutButton, zButtons)
+		m.scene.add(&m.levSelButton, zButtons)
+		m.scene.add(&m.volButton, zButtons)
+		g.levels["Main Menu"] = m
 	}
-	g.stateMachine.state = menuStruct
+	g.stateMachine.state = g.levels["Main Menu"]
 }
 
 func (m *MainMenu) Init(g *Game) {
@@ -56,7 +70,5 @@ func (m *MainMenu) Update(g *Game) {
 }
 
 func (m *MainMenu) Draw(g *Game, screen *ebiten.Image) {
-	for _, element := range g.menuSprites {
-		element.draw(screen)
-	}
+	m.scene.draw(screen)
 }
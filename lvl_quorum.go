@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Speed autoinducers drift toward the sensor kinase, in units per second, on top of their random walk
+const autoinducerDrift = 60
+
+// Fastest an autoinducer's random walk moves it along each axis, in units per second
+const autoinducerJitter = 180
+
+type QuorumLevel struct {
+	// QUORUM SENSING SPRITES
+	plasmaBg          StillImage
+	bacteria          [3]Enzyme
+	autoinducers      []StillImage
+	sensorKinase      Receptor
+	responseRegulator TFA
+	infoButton        InfoPage
+	otherToMenuButton Button
+	message           string
+	scene             *SceneGraph
+}
+
+func newQuorumLevel(g *Game) {
+	if len(g.quorumSprites) == 0 {
+		q := &QuorumLevel{
+			plasmaBg: newStillImage("PlasmaBg.png", newRect(0, 0, 1250, 750)),
+			bacteria: [3]Enzyme{
+				newEnzyme("codonButton.png", newRect(100, 220, 192, 106), "Bacterium"),
+				newEnzyme("codonButton.png", newRect(850, 220, 192, 106), "Bacterium"),
+				newEnzyme("codonButton.png", newRect(300, 560, 192, 106), "Bacterium"),
+			},
+			sensorKinase:      newReceptor("inact_receptorC.png", "act_receptorC.png", newRect(550, 300, 220, 360), "sensorHK"),
+			responseRegulator: newTFA("inact_TFA.png", "act_TFA.png", newRect(850, 550, 215, 158), "rr"),
+			message: "WELCOME TO THE BACTERIAL MEMBRANE! \n" +
+				"Click neighbouring bacteria to release \n" +
+				"autoinducers until quorum is reached!",
+		}
+		q.infoButton = infoButton
+		q.otherToMenuButton = otherToMenuButton
+
+		g.quorumSprites = []Updatable{
+			&q.bacteria[0], &q.bacteria[1], &q.bacteria[2], &q.sensorKinase, &q.responseRegulator,
+			&q.otherToMenuButton, &q.infoButton,
+		}
+
+		q.scene = newSceneGraph()
+		q.scene.add(&q.plasmaBg, zBackground)
+		for x := range q.bacteria {
+			q.scene.add(&q.bacteria[x], zWorld)
+		}
+		q.scene.add(&q.sensorKinase, zWorld)
+		q.scene.add(&q.responseRegulator, zWorld)
+		// Autoinducers come and go, so they are drawn as one node
+		q.scene.add(DrawFunc(func(screen *ebiten.Image) {
+			for _, ai := range q.autoinducers {
+				ai.draw(screen)
+			}
+		}), zWorld)
+		q.scene.add(DrawFunc(q.DrawText), zText)
+		q.scene.add(&q.otherToMenuButton, zButtons)
+		q.scene.add(&q.infoButton, zOverlay)
+		g.levels["Quorum Sensing"] = q
+	}
+	g.stateMachine.state = g.levels["Quorum Sensing"]
+}
+
+func (q *QuorumLevel) Init(g *Game) {
+	q.autoinducers = nil
+	g.state_array = g.quorumSprites
+}
+
+// Releases an autoinducer molecule from a bacterium
+func (q *QuorumLevel) Secrete(b *Enzyme) {
+	ai := StillImage{Sprite: newSprite("signalC.png", newRect(b.rect.pos.x+80, b.rect.pos.y+40, 38, 69), 0.2)}
+	q.autoinducers = append(q.autoinducers, ai)
+}
+
+func (q *QuorumLevel) Update(g *Game) {
+	q.otherToMenuButton.update(g)
+	q.infoButton.update()
+	q.sensorKinase.update()
+
+	switch pathwaySim.Quorum.Phase {
+	case sim.Sensing:
+		for x := range q.bacteria {
+			q.bacteria[x].update()
+			if q.bacteria[x].is_clicked_on {
+				q.bacteria[x].is_clicked_on = false
+				q.Secrete(&q.bacteria[x])
+			}
+		}
+		// Bacteria also secrete slowly on their own, like a low cell density
+		if res := pathwaySim.Step(sim.Input{Action: sim.Sense, Index: len(q.bacteria)}); res.Picked >= 0 {
+			q.Secrete(&q.bacteria[res.Picked])
+		}
+		// Autoinducers drift randomly, biased toward the sensor kinase, and bind on contact
+		sensorPos := q.sensorKinase.rect.pos
+		rng := pathwaySim.Rand()
+		remaining := q.autoinducers[:0]
+		for _, ai := range q.autoinducers {
+			ai.rect.pos.move((2*rng.Float64()-1)*autoinducerJitter, (2*rng.Float64()-1)*autoinducerJitter)
+			bias := newVector(-autoinducerDrift, -autoinducerDrift)
+			if ai.rect.pos.x < sensorPos.x+100 {
+				bias.x = autoinducerDrift
+			}
+			if ai.rect.pos.y < sensorPos.y+50 {
+				bias.y = autoinducerDrift
+			}
+			ai.rect.pos.move(bias.x, bias.y)
+			if !aabb_collision(ai.rect, q.sensorKinase.rect) {
+				remaining = append(remaining, ai)
+			} else if pathwaySim.Step(sim.Input{Action: sim.BindAutoinducer}).Accepted && pathwaySim.Quorum.Phase == sim.Relaying {
+				q.sensorKinase.animate()
+			}
+		}
+		q.autoinducers = remaining
+	case sim.Relaying:
+		var b_pos = cursorVector()
+		if rect_point_collision(q.sensorKinase.rect, b_pos) && input.isJustPressed() &&
+			pathwaySim.Step(sim.Input{Action: sim.Relay}).Accepted {
+			q.responseRegulator.activate()
+		}
+	case sim.Responding:
+		// Phosphorylated response regulator binds the operon's promoter
+		q.responseRegulator.rect.pos.move(0, descendSpeed)
+		if q.responseRegulator.rect.pos.y > screenHeight {
+			ToNucleus(g)
+		}
+	}
+}
+
+func (q *QuorumLevel) Draw(g *Game, screen *ebiten.Image) {
+	q.scene.draw(screen)
+}
+
+func (q *QuorumLevel) DrawText(screen *ebiten.Image) {
+	defaultFont.drawFont(screen, q.message, 75, 50, color.RGBA{220, 75, 100, 50})
+	switch pathwaySim.Quorum.Phase {
+	case sim.Sensing:
+		defaultFont.drawFont(screen, "Autoinducer bound: "+fmt.Sprint(pathwaySim.Quorum.Bound)+"/"+fmt.Sprint(sim.QuorumThreshold), 75, 200, color.Black)
+	case sim.Relaying:
+		defaultFont.drawFont(screen, "QUORUM REACHED! Click the sensor\nkinase to phosphorylate the\nresponse regulator!", 75, 200, color.Black)
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
 
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 type ReceptionLevel struct {
@@ -12,128 +15,227 @@ type ReceptionLevel struct {
 	plasmaBg          Parallax
 	plasmaMembrane    Parallax
 	signal            Signal
-	receptorA         Receptor
-	receptorB         Receptor
-	receptorC         Receptor
-	receptorD         Receptor
-	temp_tk1A         Kinase
-	temp_tk1B         Kinase
-	temp_tk1C         Kinase
-	temp_tk1D         Kinase
+	receptors         []*Receptor // Drawn for this run's generated pockets
+	kinases           []*Kinase   // One under each receptor, released when it binds the signal
+	membrane          *Membrane
+	proteins          []*MembraneProtein // Each receptor's place in the membrane
 	infoButton        InfoPage
 	otherToMenuButton Button
+	rotateButton      Button
 	message           string
+	scene             *SceneGraph
+	dragDrop          *DragDrop
+	signalNode        *Node
+	bound             int    // Receptor holding the signal, or -1
+	binding           string // Strength of the latest binding, told to the player
 }
 
-var receptionStruct *ReceptionLevel
-var receptors []*Receptor
-var receptorKinase map[*Receptor]*Kinase
-
-// Signal stores 4 different shapes
-func matchSR(signalType string, receptorType string) bool {
-	return signalReceptor[signalType] == receptorType
-}
-
-var signalReceptor = map[string]string{
-	"signalA":"receptorA", 
-	"signalB":"receptorB", 
-	"signalC":"receptorC", 
-	"signalD":"receptorD", 
-}
-
+// Shortest gap between neighbouring receptors on the membrane
+const receptorSpacing = 150
 
 func newReceptionLevel(g *Game) {
 	if len(g.receptionSprites) == 0 {
-		receptionStruct = &ReceptionLevel{
+		r := &ReceptionLevel{
 			protoPlasmaBg:  newStillImage("PlasmaBg.png", newRect(0, 0, 1250, 750)),
-			plasmaBg:       newParallax("ParallaxPlasma.png", newRect(100, 100, 1250, 750), 4),
-			plasmaMembrane: newParallax("plasmaMembrane.png", newRect(100, 300, 1250, 750), 2),
-
-			receptorA: newReceptor("inact_receptorA.png", "act_receptorA.png", newRect(50, 400, 100, 100), "receptorA"),
-			receptorB: newReceptor("inact_receptorB.png", "act_receptorB.png", newRect(350, 400, 100, 100), "receptorB"),
-			receptorC: newReceptor("inact_receptorC.png", "act_receptorC.png", newRect(650, 400, 100, 100), "receptorC"),
-			receptorD: newReceptor("inact_receptorD.png", "act_receptorD.png", newRect(950, 400, 100, 100), "receptorD"),
-
-			temp_tk1A: newKinase("inact_TK1.png", "act_TK1.png", newRect(50, 600, 150, 150), "temp_tk1A"),
-			temp_tk1B: newKinase("inact_TK1.png", "act_TK1.png", newRect(350, 600, 150, 150), "temp_tk1B"),
-			temp_tk1C: newKinase("inact_TK1.png", "act_TK1.png", newRect(650, 600, 150, 150), "temp_tk1C"),
-			temp_tk1D: newKinase("inact_TK1.png", "act_TK1.png", newRect(950, 600, 150, 150), "temp_tk1D"),
-			
+			plasmaBg:       newParallax("Signal Reception", "ParallaxPlasma.png", newRect(100, 100, 1250, 750), 4),
+			plasmaMembrane: newParallax("Signal Reception", "plasmaMembrane.png", newRect(100, 300, 1250, 750), 2),
+
 			message: 
 				"WELCOME TO THE PLASMA MEMBRANE! \n" +
-				"Drag the signal to the matching \n" +
-				"receptor to enter the cell!",
+				"Turn the signal with Q and E, then \n" +
+				"drag it into the receptor it fits! \n" +
+				"Poor fits soon fall off again, and \n" +
+				"receptors that pair up activate faster.",
 		}
 
-		receptionStruct.infoButton = infoButton
-		receptionStruct.otherToMenuButton = otherToMenuButton
-
-		switch seedSignal {
-		case 1:
-			receptionStruct.signal = newSignal("signalA.png", newRect(500, 100, 100, 100))
-			receptionStruct.signal.signalType = "signalA"
-			template = [5]string{"TAC", randomDNACodon(), randomDNACodon(), randomDNACodon(), "ACT"}
-		case 2:
-			receptionStruct.signal = newSignal("signalB.png", newRect(500, 100, 100, 100))
-			receptionStruct.signal.signalType = "signalB"
-			template = [5]string{"TAC", randomDNACodon(), randomDNACodon(), randomDNACodon(), "ATT"}
-		case 3:
-			receptionStruct.signal = newSignal("signalC.png", newRect(500, 100, 100, 100))
-			receptionStruct.signal.signalType = "signalC"
-			template = [5]string{"TAC", randomDNACodon(), randomDNACodon(), randomDNACodon(), "ATC"}
-		case 4:
-			receptionStruct.signal = newSignal("signalD.png", newRect(500, 100, 100, 100))
-			receptionStruct.signal.signalType = "signalD"
-			template = [5]string{"TAC", randomDNACodon(), randomDNACodon(), randomDNACodon(), "ATT"}
-		}
+		r.infoButton = infoButton
+		r.otherToMenuButton = otherToMenuButton
 
-		g.receptionSprites = []GUI{
-			&receptionStruct.protoPlasmaBg, &receptionStruct.plasmaBg, &receptionStruct.plasmaMembrane,
-			&receptionStruct.signal, &receptionStruct.receptorA, &receptionStruct.receptorB,
-			&receptionStruct.receptorC, &receptionStruct.receptorD, &receptionStruct.temp_tk1A,
-			&receptionStruct.temp_tk1B, &receptionStruct.temp_tk1C, &receptionStruct.temp_tk1D,
-			&receptionStruct.otherToMenuButton, &receptionStruct.infoButton,
+		// Receptors are spread along the membrane, which runs past both edges of the screen
+		sites := pathwaySim.Receptors
+		spacing := float64(receptorSpacing)
+		if len(sites) > 1 {
+			spacing = max(spacing, screenWidth*8/7/float64(len(sites)-1))
+		}
+		last := screenWidth/7 + spacing*float64(len(sites)-1)
+		r.membrane = newMembrane(screenWidth/7-receptorSpacing, last+receptorWidth+receptorSpacing)
+		for x, site := range sites {
+			posX := screenWidth/7 + spacing*float64(x)
+			receptor := newShapedReceptor(site, newVector(posX, float64(400+50*(x%2))))
+			kinase := newKinase("inact_TK1.png", "act_TK1.png", newRect(posX, 600, 150, 150), "temp_tk1"+fmt.Sprint(x+1))
+			kinase.anchor = &Vector{posX, float64(600 + 50*(x%2))}
+			r.receptors = append(r.receptors, &receptor)
+			r.kinases = append(r.kinases, &kinase)
+			r.proteins = append(r.proteins, r.membrane.add(receptor.anchor))
+		}
+		// Paired receptors cross-phosphorylate, so a signal bound to one activates TK1 faster
+		r.membrane.dimerize = func(a, b *MembraneProtein) {
+			for x, p := range r.proteins {
+				if p == a || p == b {
+					pathwaySim.Step(sim.Input{Action: sim.Dimerize, Target: r.receptors[x].receptorType})
+				}
+			}
 		}
 
-		receptors = []*Receptor{&receptionStruct.receptorA, &receptionStruct.receptorB, &receptionStruct.receptorC, &receptionStruct.receptorD}
-		receptorKinase = map[*Receptor]*Kinase{
-			&receptionStruct.receptorA: &receptionStruct.temp_tk1A,
-			&receptionStruct.receptorB: &receptionStruct.temp_tk1B,
-			&receptionStruct.receptorC: &receptionStruct.temp_tk1C,
-			&receptionStruct.receptorD: &receptionStruct.temp_tk1D,
+		// Signal is cut to one of the pockets; only that receptor will bind it
+		r.signal = newSignal(pathwaySim.Ligand, newRect(500, 100, ligandSize, ligandSize))
+		r.signal.angle = float64(rotateStep * (1 + pathwaySim.Rand().Intn(360/rotateStep-1)))
+		r.rotateButton = newLabelButton("codonButton.png", newRect(1030, 190, 192, 106), func(g *Game) {
+			if !r.signal.locked {
+				r.signal.rotate(rotateStep)
+			}
+		}, "Rotate")
+
+		g.receptionSprites = []Updatable{&r.plasmaBg, &r.plasmaMembrane}
+		for x := range r.receptors {
+			g.receptionSprites = append(g.receptionSprites, r.receptors[x], r.kinases[x])
 		}
+		g.receptionSprites = append(g.receptionSprites, &r.otherToMenuButton, &r.rotateButton, &r.infoButton)
+
+		r.buildScene()
+		g.levels["Signal Reception"] = r
 	}
-	g.stateMachine.state = receptionStruct
+	g.stateMachine.state = g.levels["Signal Reception"]
+}
+
+func (r *ReceptionLevel) buildScene() {
+	r.scene = newSceneGraph()
+	r.scene.add(&r.protoPlasmaBg, zBackground)
+	r.scene.add(&r.plasmaBg, zBackground)
+	r.scene.add(&r.plasmaMembrane, zBackground)
+	r.scene.add(DrawFunc(r.DrawRafts), zBackground)
+	receptorNodes := make([]*Node, len(r.receptors))
+	for x, receptor := range r.receptors {
+		receptorNodes[x] = r.scene.add(receptor, zWorld)
+	}
+	for _, kinase := range r.kinases {
+		r.scene.add(kinase, zWorld)
+	}
+	r.scene.add(DrawFunc(r.DrawMembrane), zWorld)
+	r.scene.add(DrawFunc(r.DrawBinding), zWorld)
+	r.scene.add(DrawFunc(effects.draw), zHighlight)
+	r.scene.add(DrawFunc(func(screen *ebiten.Image) {
+		defaultFont.drawFont(screen, r.message, 75, 50, color.RGBA{220, 75, 100, 50})
+		defaultFont.drawFont(screen, r.binding, 75, 230, color.RGBA{40, 20, 80, 255})
+	}), zText)
+	r.scene.add(&r.otherToMenuButton, zButtons)
+	r.scene.add(&r.rotateButton, zButtons)
+	r.scene.add(&r.infoButton, zOverlay)
+
+	// Each receptor's pocket is a drop target for the signal. Any the signal fits well enough
+	// at its angle holds it, for a time set by how well it fits.
+	r.signalNode = r.scene.add(&r.signal, zCarried)
+	r.bound = -1
+	offset := newVector(pocketCentreX-ligandSize/2, pocketCentreY-ligandSize/2)
+	var targets []DropTarget
+	for x, receptor := range r.receptors {
+		x, receptor := x, receptor
+		targets = append(targets, newTarget(&receptor.rect, func(d Draggable) bool {
+			bind := sim.Input{Action: sim.BindSignal, Target: receptor.receptorType, Index: int(r.signal.angle)}
+			if !pathwaySim.Step(bind).Accepted {
+				return false
+			}
+			receptor.animate()
+			pocket := newVector(receptor.rect.pos.x+pocketCentreX, receptor.rect.pos.y+pocketCentreY)
+			effects.burst("binding", pocket, pocket)
+			r.bound = x
+			affinity := pathwaySim.Affinity
+			r.binding = fmt.Sprintf("Fit %.0f%%: Kd %.3g nM,\nbound for about %.2g s", affinity*100, sim.Kd(affinity), 1/sim.OffRate(affinity))
+			// Bound signal rides on the receptor as the membrane shifts
+			r.signalNode.follow(receptorNodes[x], offset)
+			return true
+		}).snapAt(offset).shaped(receptor.site))
+	}
+	r.dragDrop = newDragDrop([]Draggable{&r.signal}, targets...)
+	r.scene.add(DrawFunc(r.dragDrop.drawHover), zHighlight)
 }
 
 func (r *ReceptionLevel) Init(g *Game) {
 	g.state_array = g.receptionSprites
 }
 
+// Keeps the signal on its receptor for one step. A strong fit activates the receptor's TK1
+// before it lets go; a weak one levels off short of it, then falls off and drifts back.
+func (r *ReceptionLevel) Dwell() {
+	if pathwaySim.Step(sim.Input{Action: sim.Dwell}).Accepted {
+		if pathwaySim.Stage == sim.Transduction && !r.kinases[r.bound].is_moving {
+			r.kinases[r.bound].activate()
+			// Having passed the signal on, the receptor is taken into the cell with its ligand
+			r.membrane.internalize(r.proteins[r.bound])
+		}
+		return
+	}
+	r.receptors[r.bound].rest()
+	r.scene.free(r.signalNode)
+	r.signal.locked, r.signal.returning = false, true
+	r.binding += "\n...and it fell off!"
+	r.bound = -1
+}
+
+// Shades the lipid rafts receptors gather in
+func (r *ReceptionLevel) DrawRafts(screen *ebiten.Image) {
+	for _, raft := range r.membrane.rafts {
+		pos := swayed(newVector(raft.lo, 520), 4)
+		vector.DrawFilledRect(screen, float32(pos.x), float32(pos.y), float32(raft.hi-raft.lo), 60, color.RGBA{240, 200, 90, 90}, true)
+	}
+}
+
+// Joins the stalks of paired receptors and draws vesicles around endocytosed ones
+func (r *ReceptionLevel) DrawMembrane(screen *ebiten.Image) {
+	for x, p := range r.proteins {
+		rect := r.receptors[x].rect
+		if p.internalized {
+			vector.StrokeCircle(screen, float32(rect.pos.x+pocketCentreX), float32(rect.pos.y+pocketCentreY), 95, 4, color.RGBA{240, 200, 90, 255}, true)
+		}
+		if p.partner != nil && p.partner.anchor.x > p.anchor.x {
+			y := float32(rect.pos.y + receptorHeadHeight + 30)
+			x1 := float32(rect.pos.x + receptorStalkX + receptorStalkW)
+			vector.StrokeLine(screen, x1, y, x1+receptorWidth-receptorStalkW, y, 8, color.RGBA{90, 110, 200, 255}, true)
+		}
+	}
+}
+
+// Shows how far the bound receptor has activated its TK1
+func (r *ReceptionLevel) DrawBinding(screen *ebiten.Image) {
+	if r.bound == -1 || pathwaySim.Stage != sim.Reception {
+		return
+	}
+	kinase := r.kinases[r.bound]
+	x, y := float32(kinase.rect.pos.x), float32(kinase.rect.pos.y-20)
+	vector.DrawFilledRect(screen, x, y, 150, 12, color.RGBA{40, 20, 80, 120}, false)
+	vector.DrawFilledRect(screen, x, y, float32(150*min(pathwaySim.Activation/sim.ActivationThreshold, 1)), 12, color.RGBA{70, 190, 120, 255}, false)
+}
+
 func (r *ReceptionLevel) Update(g *Game) {
+	r.membrane.update()
+	for x, kinase := range r.kinases {
+		kinase.anchor.x = r.proteins[x].anchor.x
+	}
 	for _, element := range g.receptionSprites {
 		element.update(g)
 	}
-
-	for _, receptor := range receptors {
-		if receptor.is_touching_signal {
-			if matchSR(r.signal.signalType, receptor.receptorType) {
-				receptor.animate()
-				r.signal.bind(receptor)
-				receptorKinase[receptor].activate()
-			}	
+	if !r.signal.locked {
+		if input.isKeyJustPressed(ebiten.KeyQ) {
+			r.signal.rotate(-rotateStep)
+		}
+		if input.isKeyJustPressed(ebiten.KeyE) {
+			r.signal.rotate(rotateStep)
 		}
 	}
 
-	if r.temp_tk1A.rect.pos.y >= screenHeight || r.temp_tk1B.rect.pos.y >= screenHeight || r.temp_tk1C.rect.pos.y >= screenHeight || r.temp_tk1D.rect.pos.y >= screenHeight {
-		ToCyto1(g)
+	r.dragDrop.update()
+	if r.bound != -1 && pathwaySim.Stage == sim.Reception {
+		r.Dwell()
+	}
+
+	for _, kinase := range r.kinases {
+		if kinase.rect.pos.y >= screenHeight {
+			ToCyto1(g)
+			break
+		}
 	}
 }
 
 func (r *ReceptionLevel) Draw(g *Game, screen *ebiten.Image) {
-	for _, element := range g.receptionSprites {
-		if element != &r.infoButton{element.draw(screen)}
-	}
-	defaultFont.drawFont(screen, r.message, 75, 50, color.RGBA{220, 75, 100, 50})
-	r.infoButton.draw(screen)
+	r.scene.draw(screen)
 }
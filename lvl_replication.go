@@ -0,0 +1,245 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type ReplicationLevel struct {
+	// REPLICATION SPRITES
+	nucleusBg         StillImage
+	dnaStrand         StillImage
+	leadingTemplate   [3]Template
+	laggingTemplate   [3]Template
+	templateBases     [9]Nucleobase
+	newBases          [9]Nucleobase
+	helicase          Enzyme
+	primase           Enzyme
+	dnaPolymerase     Enzyme
+	ligase            Enzyme
+	rightChoice       CodonChoice
+	wrongChoice1      CodonChoice
+	wrongChoice2      CodonChoice
+	infoButton        InfoPage
+	otherToMenuButton Button
+	message           string
+	scene             *SceneGraph
+	basesNode         *Node
+	enzymeNodes       map[string]*Node // Enzyme shown in each phase; both strands use "polymerase"
+	choicesNode       *Node
+	dragDrop          *DragDrop
+
+	strand    string     // Strand whose bases are laid out, which the fork may have moved on from
+	doneTimer float64    // Seconds since the fork finished
+	spots     [3]float64 // Where the codon choices wait, in the order last shuffled
+}
+
+// Seconds the finished fork stays on screen before the pathway moves on to transcription
+const replicationDoneTime = 2.0
+
+func newReplicationLevel(g *Game) {
+	if len(g.replicationSprites) == 0 {
+		r := &ReplicationLevel{
+			nucleusBg: newStillImage(nucleusBackground, newRect(0, 0, 1250, 750)),
+			dnaStrand: newStillImage("DNA.png", newRect(0, 400, 1250, 262)),
+			spots:     choiceSpots,
+
+			helicase:      newDrawnEnzyme(newRect(1050, 200, 0, 0), "Helicase"),
+			primase:       newDrawnEnzyme(newRect(1060, 220, 0, 0), "Primase"),
+			dnaPolymerase: newDrawnEnzyme(newRect(255, 150, 0, 0), "DNA Pol"),
+			ligase:        newDrawnEnzyme(newRect(1050, 200, 0, 0), "Ligase"),
+			message: "WELCOME TO DNA REPLICATION! \n" +
+				"Click each enzyme, then drag the \n" +
+				"complementary DNA codon to DNA \n" +
+				"polymerase to copy both strands!!",
+		}
+
+		// Codons are filled in from the simulation's fork when the level starts
+		for x := 0; x < 3; x++ {
+			r.leadingTemplate[x] = newTemplate("DNA.png", newRect(float64(275+(225*x)), 400, 150, 150), "TAC", x)
+			r.laggingTemplate[x] = newTemplate("DNA.png", newRect(float64(275+(225*x)), 400, 150, 150), "ATG", x)
+		}
+
+		r.rightChoice = newCodonChoice("codonButton.png", newRect(100, 600, 192, 111), "ATG")
+		r.wrongChoice1 = newCodonChoice("codonButton.png", newRect(400, 600, 192, 111), "ATG")
+		r.wrongChoice2 = newCodonChoice("codonButton.png", newRect(700, 600, 192, 111), "ATG")
+		r.infoButton = infoButton
+		r.otherToMenuButton = otherToMenuButton
+
+		g.replicationSprites = []Updatable{
+			&r.helicase, &r.primase,
+			&r.dnaPolymerase, &r.ligase,
+			&r.rightChoice, &r.wrongChoice1,
+			&r.wrongChoice2, &r.otherToMenuButton,
+			&r.infoButton,
+		}
+		r.buildScene()
+		g.levels["DNA Replication"] = r
+	}
+	g.stateMachine.state = g.levels["DNA Replication"]
+}
+
+func (r *ReplicationLevel) buildScene() {
+	r.scene = newSceneGraph()
+	r.scene.add(&r.nucleusBg, zBackground)
+	r.scene.add(&r.dnaStrand, zBackground)
+	// Bases are only exposed once helicase has unwound the helix
+	r.basesNode = r.scene.root.group(zWorld)
+	for x := range r.templateBases {
+		r.basesNode.add(&r.templateBases[x], zWorld)
+	}
+	for x := range r.newBases {
+		r.basesNode.add(&r.newBases[x], zWorld)
+	}
+	r.basesNode.add(DrawFunc(r.DrawPrimers), zWorld)
+	r.enzymeNodes = map[string]*Node{
+		"helicase":   r.scene.add(&r.helicase, zWorld),
+		"primase":    r.scene.add(&r.primase, zWorld),
+		"polymerase": r.scene.add(&r.dnaPolymerase, zWorld),
+		"ligase":     r.scene.add(&r.ligase, zWorld),
+	}
+	r.choicesNode = r.scene.root.group(zCarried)
+	r.rightChoice.addTo(r.choicesNode, zCarried)
+	r.wrongChoice1.addTo(r.choicesNode, zCarried)
+	r.wrongChoice2.addTo(r.choicesNode, zCarried)
+	r.scene.add(DrawFunc(r.DrawText), zText)
+	r.scene.add(&r.otherToMenuButton, zButtons)
+	r.scene.add(&r.infoButton, zOverlay)
+
+	// Codon dropped on DNA polymerase is checked against the template by the simulation
+	polymerase := newTarget(&r.dnaPolymerase.rect, func(d Draggable) bool {
+		fork := &pathwaySim.Fork
+		strand, frag := fork.Strand, fork.Fragment
+		if !pathwaySim.Step(sim.Input{Action: sim.PlaceDNACodon, Target: d.(*CodonChoice).codon}).Accepted {
+			return false
+		}
+		r.Templates()[frag].is_complete = true
+		r.Synthesize(frag)
+		if fork.Phase == strand {
+			r.ResetChoices()
+		}
+		return true
+	})
+	r.dragDrop = newDragDrop([]Draggable{&r.rightChoice, &r.wrongChoice1, &r.wrongChoice2}, polymerase)
+	r.scene.add(DrawFunc(r.dragDrop.drawHover), zHighlight)
+}
+
+func (r *ReplicationLevel) Init(g *Game) {
+	r.doneTimer = 0
+	fork := &pathwaySim.Fork
+	for x := 0; x < 3; x++ {
+		r.leadingTemplate[x].codon, r.leadingTemplate[x].is_complete = fork.Leading[x], false
+		r.laggingTemplate[x].codon, r.laggingTemplate[x].is_complete = fork.Lagging[x], false
+	}
+	r.SetStrand(fork.Strand)
+	g.state_array = g.replicationSprites
+}
+
+// Returns the template codons of the strand whose bases are laid out
+func (r *ReplicationLevel) Templates() *[3]Template {
+	if r.strand == sim.Lagging {
+		return &r.laggingTemplate
+	}
+	return &r.leadingTemplate
+}
+
+// Lays out the template bases of a strand and clears the new strand being synthesized on it
+func (r *ReplicationLevel) SetStrand(strand string) {
+	r.strand = strand
+	templates := r.Templates()
+	for x := 0; x < len(r.templateBases); x++ {
+		base := string(templates[x/3].codon[x%3])
+		posX := float64(275 + (75 * x))
+		r.templateBases[x] = newNucleobase(base, newRect(posX, 400, 65, 150), x, true)
+		r.newBases[x] = newNucleobase("N/A", newRect(posX+65, 400, 65, 150), x, false)
+	}
+}
+
+func (r *ReplicationLevel) ResetChoices() {
+	frag := pathwaySim.Fork.Fragment
+	curr := &r.Templates()[frag]
+	pathwaySim.Rand().Shuffle(len(r.spots), func(i, j int) { r.spots[i], r.spots[j] = r.spots[j], r.spots[i] })
+	r.rightChoice.reset(r.spots[0], 600, sim.Replicate(curr.codon))
+	r.wrongChoice1.reset(r.spots[1], 600, pathwaySim.RandomDNAChoice(r.rightChoice.codon))
+	r.wrongChoice2.reset(r.spots[2], 600, pathwaySim.RandomDNAChoice(r.rightChoice.codon))
+	r.dnaPolymerase.rect.pos.x = float64(255 + (225 * frag))
+}
+
+// Fills in the new strand bases of the completed codon
+func (r *ReplicationLevel) Synthesize(frag int) {
+	codon := sim.Replicate(r.Templates()[frag].codon)
+	for x := 0; x < 3; x++ {
+		r.newBases[(frag*3)+x].setBase(string(codon[x]))
+	}
+}
+
+func (r *ReplicationLevel) Update(g *Game) {
+	r.otherToMenuButton.update(g)
+	r.infoButton.update()
+
+	fork := &pathwaySim.Fork
+	enzymes := map[string]*Enzyme{sim.Unwinding: &r.helicase, sim.Priming: &r.primase, sim.Sealing: &r.ligase}
+	switch fork.Phase {
+	case sim.Unwinding, sim.Priming, sim.Sealing:
+		enzyme := enzymes[fork.Phase]
+		enzyme.update()
+		if enzyme.is_clicked_on {
+			enzyme.is_clicked_on = false
+			pathwaySim.Step(sim.Input{Action: sim.UseEnzyme, Target: enzyme.name})
+			// A primed fragment is ready for DNA polymerase
+			if fork.Phase == fork.Strand {
+				r.ResetChoices()
+			}
+		}
+	case sim.Leading, sim.Lagging:
+		r.dragDrop.update()
+		for _, c := range []*CodonChoice{&r.rightChoice, &r.wrongChoice1, &r.wrongChoice2} {
+			c.update()
+		}
+	case sim.Replicated:
+		r.doneTimer += fixedStep
+		if r.doneTimer > replicationDoneTime {
+			ToNucleus(g)
+		}
+	}
+	// Lagging strand is laid out as soon as the leading strand is done
+	if fork.Strand != r.strand {
+		r.SetStrand(fork.Strand)
+	}
+}
+
+func (r *ReplicationLevel) Draw(g *Game, screen *ebiten.Image) {
+	phase := pathwaySim.Fork.Phase
+	if phase == sim.Leading || phase == sim.Lagging {
+		phase = "polymerase"
+	}
+	r.basesNode.visible = phase != "helicase"
+	for name, node := range r.enzymeNodes {
+		node.visible = name == phase
+	}
+	r.choicesNode.visible = phase == "polymerase"
+	r.scene.draw(screen)
+}
+
+func (r *ReplicationLevel) DrawPrimers(screen *ebiten.Image) {
+	for x, primed := range pathwaySim.Fork.Primers {
+		if primed {
+			defaultFont.drawFont(screen, "primer", float64(275+(225*x)), 270, color.RGBA{200, 0, 0, 255})
+		}
+	}
+}
+
+func (r *ReplicationLevel) DrawText(screen *ebiten.Image) {
+	switch pathwaySim.Fork.Phase {
+	case sim.Leading, sim.Lagging:
+		defaultFont.drawFont(screen, "Copying the "+r.strand+" strand", 75, 200, color.Black)
+	case sim.Sealing:
+		defaultFont.drawFont(screen, "Seal the Okazaki fragments!", 75, 200, color.Black)
+	case sim.Replicated:
+		defaultFont.drawFont(screen, "REPLICATION COMPLETE!", 75, 200, color.Black)
+	}
+
+	defaultFont.drawFont(screen, r.message, 75, 50, color.Black)
+}
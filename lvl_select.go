@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 // Sprites in Level Selection
@@ -13,27 +15,61 @@ type LevelSelection struct {
 	levToCyto1Button   Button
 	levToNucleusButton Button
 	levToCyto2Button   Button
+	levToReplicationButton Button
+	prokaryoteButton       Button
+	errorRateButton        Button
+	scene                  *SceneGraph
 }
 
-var levSelStruct *LevelSelection
-
-// Initialize level selection struct and levSelSprites array if not initialized, then set state to levSelStruct
+// Initialize level selection struct and levSelSprites array if not initialized, then set state to it
 func newLevelSelection(g *Game) {
 	if len(g.levSelSprites) == 0 {
-		levSelStruct = &LevelSelection{
+		l := &LevelSelection{
 			levSelBg: newStillImage("levSelBg.png", newRect(0, 0, 1250, 750)),
 			levToMenuButton: newButton("menuButton.png", newRect(250, 190, 300, 200), ToMenu),
 			levToPlasmaButton: newButton("levToPlasmaBtn.png", newRect(520, 110, 300, 180), ToPlasma),
 			levToCyto1Button: newButton("levToCyto1Btn.png", newRect(820, 110, 300, 180), ToCyto1),
 			levToNucleusButton: newButton("levToNucleusBtn.png", newRect(520, 285, 300, 180), ToNucleus),
 			levToCyto2Button: newButton("levToCyto2Btn.png", newRect(820, 285, 300, 180), ToCyto2),
+			levToReplicationButton: newLabelButton("codonButton.png", newRect(545, 460, 240, 132), ToReplication, "Replication"),
+			prokaryoteButton: newLabelButton("codonButton.png", newRect(845, 460, 240, 132), ToggleProkaryote, cellModeLabel()),
+			errorRateButton: newLabelButton("codonButton.png", newRect(245, 460, 240, 132), CycleErrorRate, errorRateLabel()),
 		}
-		g.levSelSprites = []GUI{
-			&levSelStruct.levSelBg, &levSelStruct.levToMenuButton, &levSelStruct.levToPlasmaButton, &levSelStruct.levToCyto1Button,
-			&levSelStruct.levToNucleusButton, &levSelStruct.levToCyto2Button,	
+
+		l.scene = newSceneGraph()
+		l.scene.add(&l.levSelBg, zBackground)
+		for _, button := range []*Button{
+			&l.levToMenuButton, &l.levToPlasmaButton, &l.levToCyto1Button,
+			&l.levToNucleusButton, &l.levToCyto2Button, &l.levToReplicationButton,
+			&l.prokaryoteButton, &l.errorRateButton,
+		} {
+			g.levSelSprites = append(g.levSelSprites, button)
+			l.scene.add(button, zButtons)
 		}
+		g.levels["Level Selection"] = l
 	}
-	g.stateMachine.state = levSelStruct
+	g.stateMachine.state = g.levels["Level Selection"]
+}
+
+// Switches every stage of the pathway between eukaryote and prokaryote cells
+func ToggleProkaryote(g *Game) {
+	prokaryoteMode = !prokaryoteMode
+}
+
+func cellModeLabel() string {
+	if prokaryoteMode {
+		return "Prokaryote"
+	}
+	return "Eukaryote"
+}
+
+// Steps RNA polymerase through the error rates it can be set to for transcription
+func CycleErrorRate(g *Game) {
+	pathwaySim.Step(sim.Input{Action: sim.SetErrorRate, Index: sim.NextErrorRate(pathwaySim.Pathway.ErrorRate)})
+}
+
+func errorRateLabel() string {
+	return fmt.Sprintf("Errors %g%%", pathwaySim.Pathway.ErrorRate*100)
 }
 
 func (l *LevelSelection) Init(g *Game) {
@@ -41,15 +77,16 @@ func (l *LevelSelection) Init(g *Game) {
 }
 
 func (l *LevelSelection) Update(g *Game) {
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+	if input.isJustPressed() {
 		for _, element := range g.levSelSprites {
 			element.update(g)
 		}
 	}
+	// Labels follow the settings the buttons change
+	l.prokaryoteButton.label = cellModeLabel()
+	l.errorRateButton.label = errorRateLabel()
 }
 
 func (l *LevelSelection) Draw(g *Game, screen *ebiten.Image) {
-	for _, element := range g.levSelSprites {
-		element.draw(screen)
-	}
+	l.scene.draw(screen)
 }
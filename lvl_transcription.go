@@ -1,18 +1,16 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
-	"math/rand"
 
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
-var (
-	currentFrag = 0
-	rna         [5]Transcript
-	dna         [5]Template
-	spots       = [3]int{350, 650, 950}
-)
+// Where the three codon choices wait, before each level shuffles them
+var choiceSpots = [3]float64{350, 650, 950}
 
 type TranscriptionLevel struct {
 	// TRANSCRIPTION SPRITES
@@ -27,9 +25,22 @@ type TranscriptionLevel struct {
 	rightChoice       CodonChoice
 	wrongChoice1      CodonChoice
 	wrongChoice2      CodonChoice
+	hat               Enzyme
+	hdac              Enzyme
+	demethylase       Enzyme
 	infoButton        InfoPage
 	otherToMenuButton Button
 	message           string
+	scene             *SceneGraph
+	rnaNodes          [6]*Node
+	rnaBaseNodes      [18]*Node
+	dnaBaseNodes      [15]*Node
+	tfaNode           *Node
+	polymeraseNode    *Node
+	dragDrop          *DragDrop
+	ntps              *Emitter   // NTPs streaming into RNA polymerase while it transcribes
+	silencedTimer     float64    // Seconds since the run ended on a silenced gene
+	spots             [3]float64 // Where the codon choices wait, in the order last shuffled
 
 	// Note to self: when updating DNA image, make the sprite like plasma membrane
 	// So it can scroll to the left and show different codons, with bases as separate sprites
@@ -37,154 +48,303 @@ type TranscriptionLevel struct {
 
 }
 
-var transcriptionStruct *TranscriptionLevel
+// Seconds the silenced gene is shown before the run ends
+const silencedTime = 4.0
 
 func newTranscriptionLevel(g *Game) {
 	if len(g.transcriptionSprites) == 0 {
-		transcriptionStruct = &TranscriptionLevel{
-			nucleusBg: newStillImage("NucleusBg.png", newRect(0, 0, 1250, 750)),
+		t := &TranscriptionLevel{
+			nucleusBg: newStillImage(nucleusBackground, newRect(0, 0, 1250, 750)),
+			spots:     choiceSpots,
 
 			temp_tfa:      newTFA("inact_TFA.png", "act_TFA.png", newRect(420, -100, 150, 150), "tfa2"),
 			rnaPolymerase: newRNAPolymerase("rnaPolym.png", newRect(-400, 100, 340, 265)),
+			hat:           newEnzyme("codonButton.png", newRect(1030, 190, 192, 106), "HAT"),
+			hdac:          newEnzyme("codonButton.png", newRect(1030, 290, 192, 106), "HDAC"),
+			demethylase:   newEnzyme("codonButton.png", newRect(1030, 390, 192, 106), "Demeth."),
 			message: "WELCOME TO THE NUCLEUS! \n" +
 				"Drag the complementary RNA codon \n" +
 				"to RNA Polymerase to transcribe \n" +
 				"a new mRNA molecule!!!",
 		}
 
-		copy(transcriptionStruct.DNA[:], dna[:])
-		transcriptionStruct.DNA[5] = dna[4]
-		copy(transcriptionStruct.RNA[:], rna[:])
-		transcriptionStruct.RNA[5] = newTranscript("RNA5.png", transcriptionStruct.RNA[4].rect, transcriptionStruct.RNA[4].codon, true)
-
-		transcriptionStruct.rightChoice = newCodonChoice("codonButton.png", newRect(100, 200, 192, 111), transcribe(dna[0].codon))
-		transcriptionStruct.wrongChoice1 = newCodonChoice("codonButton.png", newRect(400, 200, 192, 111), randomRNACodon(transcriptionStruct.rightChoice.codon))
-		transcriptionStruct.wrongChoice2 = newCodonChoice("codonButton.png", newRect(700, 200, 192, 111), randomRNACodon(transcriptionStruct.rightChoice.codon))
-		transcriptionStruct.infoButton = infoButton
-		transcriptionStruct.otherToMenuButton = otherToMenuButton
-
-		g.transcriptionSprites = []GUI{
-			&transcriptionStruct.nucleusBg, &transcriptionStruct.temp_tfa,
-			&transcriptionStruct.DNA[0],
-			&transcriptionStruct.RNA[1], &transcriptionStruct.RNA[2],
-			&transcriptionStruct.RNA[3], &transcriptionStruct.RNA[4],
-			&transcriptionStruct.rnaPolymerase, &transcriptionStruct.rightChoice,
-			&transcriptionStruct.wrongChoice1, &transcriptionStruct.wrongChoice2,
-			&transcriptionStruct.otherToMenuButton, &transcriptionStruct.infoButton,
+		for x := 0; x < 5; x++ {
+			t.DNA[x] = newTemplate("DNA.png", newRect(float64(200*x), 400, 150, 150), pathwaySim.Template[x], x)
+			t.RNA[x] = newTranscript("RNA"+fmt.Sprint(x)+".png", newRect(float64(100*x), 0, 150, 150), sim.Transcribe(pathwaySim.Template[x]), true)
+		}
+		t.DNA[5] = t.DNA[4]
+		t.RNA[5] = newTranscript("RNA5.png", t.RNA[4].rect, t.RNA[4].codon, true)
+		for x := range t.RNA {
+			t.RNA[x].polymerase = &t.rnaPolymerase
 		}
+		t.rnaPolymerase.tfa = &t.temp_tfa
+		t.rnaPolymerase.onCodon = t.ResetChoices
+
+		t.rightChoice = newCodonChoice("codonButton.png", newRect(100, 200, 192, 111), sim.Transcribe(pathwaySim.Template[0]))
+		t.wrongChoice1 = newCodonChoice("codonButton.png", newRect(400, 200, 192, 111), pathwaySim.RandomRNACodon(t.rightChoice.codon))
+		t.wrongChoice2 = newCodonChoice("codonButton.png", newRect(700, 200, 192, 111), pathwaySim.RandomRNACodon(t.rightChoice.codon))
+		t.infoButton = infoButton
+		t.otherToMenuButton = otherToMenuButton
+
+		g.transcriptionSprites = []Updatable{
+			&t.temp_tfa,
+			&t.RNA[1], &t.RNA[2],
+			&t.RNA[3], &t.RNA[4],
+			&t.rnaPolymerase, &t.rightChoice,
+			&t.wrongChoice1, &t.wrongChoice2,
+			&t.hat, &t.hdac, &t.demethylase,
+			&t.otherToMenuButton, &t.infoButton,
+		}
+		t.buildScene()
+		g.levels["Transcription"] = t
 	}
-	g.stateMachine.state = transcriptionStruct
+	g.stateMachine.state = g.levels["Transcription"]
+}
+
+func (t *TranscriptionLevel) buildScene() {
+	t.scene = newSceneGraph()
+	t.scene.add(&t.nucleusBg, zBackground)
+	for x := range t.RNA {
+		t.rnaNodes[x] = t.scene.add(&t.RNA[x], zWorld)
+	}
+	t.polymeraseNode = t.scene.add(&t.rnaPolymerase, zWorld)
+	t.scene.add(&t.DNA[0], zWorld)
+	t.scene.add(DrawFunc(t.DrawMarks), zWorld)
+	t.tfaNode = t.scene.add(&t.temp_tfa, zWorld+1)
+	for x := range t.RNAbases {
+		t.rnaBaseNodes[x] = t.scene.add(&t.RNAbases[x], zWorld+1)
+	}
+	for x := range t.DNAbases {
+		t.dnaBaseNodes[x] = t.scene.add(&t.DNAbases[x], zWorld+1)
+	}
+	t.rightChoice.addTo(t.scene.root, zCarried)
+	t.wrongChoice1.addTo(t.scene.root, zCarried)
+	t.wrongChoice2.addTo(t.scene.root, zCarried)
+	t.scene.add(DrawFunc(t.DrawText), zText)
+
+	// Codon dropped on RNA polymerase is checked against the template by the simulation
+	polymerase := newTarget(&t.rnaPolymerase.rect, func(d Draggable) bool {
+		if t.rnaPolymerase.next {
+			return false
+		}
+		frag := pathwaySim.Fragment
+		res := pathwaySim.Step(sim.Input{Action: sim.PlaceCodon, Target: d.(*CodonChoice).codon})
+		if res.Accepted {
+			t.Incorporate(frag, res.Mismatch)
+			// RNA polymerase moves on once the simulation has accepted the codon
+			t.rnaPolymerase.next = true
+		}
+		return res.Accepted
+	})
+	t.dragDrop = newDragDrop([]Draggable{&t.rightChoice, &t.wrongChoice1, &t.wrongChoice2}, polymerase)
+	t.scene.add(DrawFunc(t.dragDrop.drawHover), zHighlight)
+	t.ntps = newEmitter("ntp", &t.rnaPolymerase.rect, &t.rnaPolymerase.rect)
+	t.scene.add(DrawFunc(effects.draw), zHighlight)
+	t.scene.add(&t.otherToMenuButton, zButtons)
+	t.scene.add(&t.infoButton, zOverlay)
 }
 
 func (t *TranscriptionLevel) Init(g *Game) {
-	currentFrag = 0
 	t.origRNAbases[0] = newNucleobase("N/A", newRect(0, 0, 65, 150), 0, false)
 	t.origRNAbases[1] = newNucleobase("N/A", newRect(0, 0, 65, 150), 1, false)
 	t.origRNAbases[2] = newNucleobase("N/A", newRect(0, 0, 65, 150), 2, false)
 	for x := 0; x < len(t.origRNAbases)-3; x++ {
 		base := string(t.RNA[x/3].codon[x%3])
-		posX := 125 + t.RNA[currentFrag].rect.pos.x + (50 * x)
-		posY := 250 + t.RNA[currentFrag].rect.pos.y + 220 - (15 * x)
+		posX := 125 + t.RNA[0].rect.pos.x + float64(50*x)
+		posY := 250 + t.RNA[0].rect.pos.y + 220 - float64(15*x)
 		t.origRNAbases[x+3] = newNucleobase(base, newRect(posX, posY, 65, 150), x, false)
 	}
 	t.RNAbases = t.origRNAbases
 	for x := 0; x < len(t.DNAbases); x++ {
 		base := string(t.DNA[x/3].codon[x%3])
-		posX := t.DNA[2].rect.pos.x + (50 * x)
+		posX := t.DNA[2].rect.pos.x + float64(50*x)
 		posY := t.DNA[2].rect.pos.y
 		t.DNAbases[x] = newNucleobase(base, newRect(posX, posY, 65, 150), x, true)
 	}
 	t.ResetChoices()
+	t.silencedTimer = 0
 	g.state_array = g.transcriptionSprites
 	t.temp_tfa.activate()
 }
 
 func (t *TranscriptionLevel) ResetChoices() {
-	curr := &t.DNA[currentFrag]
-	rand.Shuffle(len(spots), func(i, j int) { spots[i], spots[j] = spots[j], spots[i] })
-	t.rightChoice.reset(0, 600, transcribe(curr.codon))
-	t.wrongChoice1.reset(1, 600, randomRNACodon(t.rightChoice.codon))
-	t.wrongChoice2.reset(2, 600, randomRNACodon(t.rightChoice.codon))
-	for x := 0; x < (currentFrag+1)*3; x++ {
-		temp := (currentFrag+1)*3 - 1 - x
+	frag := pathwaySim.Fragment
+	curr := &t.DNA[frag]
+	pathwaySim.Rand().Shuffle(len(t.spots), func(i, j int) { t.spots[i], t.spots[j] = t.spots[j], t.spots[i] })
+	t.rightChoice.reset(t.spots[0], 600, sim.Transcribe(curr.codon))
+	t.wrongChoice1.reset(t.spots[1], 600, pathwaySim.RandomRNACodon(t.rightChoice.codon))
+	t.wrongChoice2.reset(t.spots[2], 600, pathwaySim.RandomRNACodon(t.rightChoice.codon))
+	for x := 0; x < (frag+1)*3; x++ {
+		temp := (frag+1)*3 - 1 - x
 		base := t.RNAbases[x]
 		base.rect.pos.y = t.origRNAbases[temp].rect.pos.y
 		t.RNAbases[x] = base
 	}
-	reset = false
 }
 
 func (t *TranscriptionLevel) Update(g *Game) {
 	t.otherToMenuButton.update(g)
-	t.RNA[currentFrag].update()
-	if currentFrag < 5 {
-		t.RNA[currentFrag+1].update()
+	frag := pathwaySim.Fragment
+	t.RNA[frag].update()
+	if frag < 5 {
+		t.RNA[frag+1].update()
 	}
 	t.infoButton.update()
-	t.temp_tfa.update()
-	t.rnaPolymerase.update(g)
-
-	curr := &t.DNA[currentFrag]
+	// A gene the cell type keeps silenced can never be read, so the run ends
+	if pathwaySim.Outcome == sim.Silenced {
+		t.silencedTimer += fixedStep
+		if t.silencedTimer > silencedTime {
+			ToMenu(g)
+		}
+		return
+	}
+	t.UpdateMarks()
+	// TF and RNA polymerase cannot bind until the gene is accessible
+	if pathwaySim.Marks.Accessible() || t.rnaPolymerase.rect.pos.x > 80 {
+		if t.rnaPolymerase.rect.pos.x >= 80 {
+			// Once bound, the TF rides on the polymerase
+			t.tfaNode.follow(t.polymeraseNode, newVector(60, 115))
+		} else {
+			t.temp_tfa.update()
+		}
+		t.rnaPolymerase.update(g)
+	}
+	t.ntps.on = t.rnaPolymerase.rect.pos.x >= 80 && pathwaySim.Fragment < 5
+	t.ntps.update()
 
-	if reset {
-		t.ResetChoices()
+	t.dragDrop.update()
+	for _, c := range []*CodonChoice{&t.rightChoice, &t.wrongChoice1, &t.wrongChoice2} {
+		c.update()
 	}
 
-	//fmt.Printf("%t\n", dna[currentFrag].is_complete)
-	t.rightChoice.update(curr)
-	t.wrongChoice1.update(curr)
-	t.wrongChoice2.update(curr)
+	// Error rate can only be changed before the first codon is transcribed
+	if input.isKeyJustPressed(ebiten.KeyE) {
+		pathwaySim.Step(sim.Input{Action: sim.SetErrorRate, Index: sim.NextErrorRate(pathwaySim.Pathway.ErrorRate)})
+	}
 
-	for i, base := range t.RNAbases {
-		base.update()
-		t.RNAbases[i] = base
+	for x := range t.RNAbases {
+		t.placeRNABase(&t.RNAbases[x])
 	}
-	// Checks if current DNA codon is complete
-	if t.DNA[currentFrag].is_complete && !t.rnaPolymerase.next {
-		nextDNACodon()
+	if pathwaySim.ErrorProne() {
+		t.Proofread()
 	}
 	if t.RNA[5].rect.pos.y <= -600 {
-		ToCyto2(g)
-		reset = false
+		t.ExportTranscript()
+		ToExport(g)
 	}
 }
 
-func (t *TranscriptionLevel) Draw(g *Game, screen *ebiten.Image) {
-	t.nucleusBg.draw(screen)
-
-	t.RNA[currentFrag].draw(screen)
-	t.rnaPolymerase.draw(screen)
-	t.DNA[0].draw(screen)
-	t.temp_tfa.draw(screen)
-	//codonFont.drawFont(screen, strings.Join(template[0:5], ""), dna[currentFrag].rect.pos.x+300, dna[currentFrag].rect.pos.y, color.Black)
-
-	t.rightChoice.draw(screen)
-	t.wrongChoice1.draw(screen)
-	t.wrongChoice2.draw(screen)
-
-	for y := 0; y < (currentFrag+1)*3; y++ {
-		t.RNAbases[y].draw(screen)
-	}
-
-	if currentFrag != -1 {
-		if currentFrag == 0 {
-			t.DNAbases[0].draw(screen)
-			t.DNAbases[1].draw(screen)
-			t.DNAbases[2].draw(screen)
-		} else if currentFrag < 5 {
-			for y := 0; y < 3; y++ {
-				t.DNAbases[(currentFrag*3)+y].draw(screen)
-			}
+// Keeps a transcribed base on the RNA as it trails out behind the polymerase
+func (t *TranscriptionLevel) placeRNABase(n *Nucleobase) {
+	frag := pathwaySim.Fragment
+	n.rect.pos.x = (675 + t.RNA[frag].rect.pos.x + float64(50*n.index)) - float64(150*(frag-1))
+	n.rect.pos.y = (t.RNA[5].rect.pos.y + 400 + float64(25*n.index)) - float64(75*(frag-1))
+}
+
+// Applies any epigenetic tool the cell type expresses that the player clicks
+func (t *TranscriptionLevel) UpdateMarks() {
+	tools := map[string]*Enzyme{"HAT": &t.hat, "HDAC": &t.hdac, "Demethylase": &t.demethylase}
+	for _, tool := range pathwaySim.Pathway.Tools {
+		tools[tool].update()
+		if tools[tool].is_clicked_on {
+			pathwaySim.Step(sim.Input{Action: sim.ApplyTool, Target: tool})
+			tools[tool].is_clicked_on = false
+		}
+	}
+}
+
+// Draws nucleosomes packing the DNA and methyl groups on methylated codons
+func (t *TranscriptionLevel) DrawMarks(screen *ebiten.Image) {
+	for x := 0; x < 5; x++ {
+		posX := float32(130 + (230 * x))
+		if pathwaySim.Marks.Chromatin == "closed" {
+			vector.DrawFilledCircle(screen, posX, 520, 70, color.RGBA{120, 60, 150, 200}, true)
+		} else {
+			vector.StrokeCircle(screen, posX, 520, 40, 4, color.RGBA{120, 60, 150, 120}, true)
+		}
+		if pathwaySim.Marks.Methylated[x] {
+			vector.DrawFilledCircle(screen, posX, 390, 22, color.RGBA{200, 0, 0, 255}, true)
+			defaultFont.drawFont(screen, "Me", float64(posX)-18, 400, color.White)
 		}
 	}
+	if sim.Contains(pathwaySim.Pathway.Tools, "HAT") {
+		t.hat.draw(screen)
+	}
+	if sim.Contains(pathwaySim.Pathway.Tools, "HDAC") {
+		t.hdac.draw(screen)
+	}
+	if sim.Contains(pathwaySim.Pathway.Tools, "Demethylase") {
+		t.demethylase.draw(screen)
+	}
+	cellType := pathwaySim.Pathway.CellType
+	if pathwaySim.Outcome == sim.Silenced {
+		defaultFont.drawFont(screen, "This gene is silenced in "+cellType+"\ncells: the signal has no effect here!\nRun over - back to the menu...", 75, 290, color.RGBA{200, 0, 0, 255})
+	} else {
+		defaultFont.drawFont(screen, cellType+" cell - chromatin "+pathwaySim.Marks.Chromatin, 75, 290, color.Black)
+	}
+}
 
-	//if currentFrag != -1 {
-	//	codonFont.drawFont(screen, dna[currentFrag].codon, dna[0].rect.pos.x+500, dna[0].rect.pos.y+50, color.Black)
-	//}
+// Marks the base the simulation misincorporated in a fragment, if any
+func (t *TranscriptionLevel) Incorporate(frag int, index int) {
+	codon := pathwaySim.Transcript[frag]
+	if index != -1 {
+		// RNAbases begins with 3 empty bases, so fragment bases start at index 3
+		base := &t.RNAbases[3+(frag*3)+index]
+		base.setBase(string(codon[index]))
+		base.is_mismatch = true
+	}
+}
 
-	defaultFont.drawFont(screen, t.message, 75, 50, color.Black)
+// Fixes a mismatched RNA base when the player clicks it before the transcript leaves
+func (t *TranscriptionLevel) Proofread() {
+	if !input.isJustPressed() {
+		return
+	}
+	var b_pos = cursorVector()
+	for y := 3; y < (pathwaySim.Fragment+1)*3; y++ {
+		base := &t.RNAbases[y]
+		if base.is_mismatch && rect_point_collision(base.hitbox(), b_pos) {
+			if pathwaySim.Step(sim.Input{Action: sim.Proofread, Index: y - 3}).Accepted {
+				frag, index := (y-3)/3, (y-3)%3
+				base.setBase(string(pathwaySim.Transcript[frag][index]))
+				base.is_mismatch = false
+			}
+		}
+	}
+}
 
-	t.otherToMenuButton.draw(screen)
+// Uncorrected errors leave the nucleus with the transcript and change the protein at translation
+func (t *TranscriptionLevel) ExportTranscript() {
+	pathwaySim.Step(sim.Input{Action: sim.Export})
+}
 
-	t.infoButton.draw(screen)
+func (t *TranscriptionLevel) Draw(g *Game, screen *ebiten.Image) {
+	frag := pathwaySim.Fragment
+	// Only the strand being transcribed, the bases made so far and the current template codon are shown
+	for x, node := range t.rnaNodes {
+		node.visible = x == frag
+	}
+	for y, node := range t.rnaBaseNodes {
+		node.visible = y < (frag+1)*3
+	}
+	for y, node := range t.dnaBaseNodes {
+		node.visible = frag != -1 && frag < 5 && y/3 == frag
+	}
+	t.scene.draw(screen)
+}
+
+func (t *TranscriptionLevel) DrawText(screen *ebiten.Image) {
+	defaultFont.drawFont(screen, t.message, 75, 50, color.Black)
+	if pathwaySim.Outcome == sim.Silenced {
+		return
+	}
+	if pathwaySim.Fragment == 0 {
+		defaultFont.drawFont(screen, "Press E to change the polymerase\nerror rate: "+errorRateLabel(), 75, 200, color.Black)
+	} else if pathwaySim.ErrorProne() {
+		defaultFont.drawFont(screen, "ERROR-PRONE MODE: click red bases to\nproofread them! Mismatches: "+fmt.Sprint(pathwaySim.MismatchCount()), 75, 200, color.RGBA{200, 0, 0, 255})
+	}
+	// A misincorporated stop codon would end translation early
+	if stop := pathwaySim.PrematureStop(); stop != -1 {
+		defaultFont.drawFont(screen, "Codon "+fmt.Sprint(stop+1)+" is now a STOP: proofread it!", 75, 340, color.RGBA{200, 0, 0, 255})
+	}
 }
@@ -1,52 +1,145 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
 
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// Copies of each kinase and TF adrift in the cytoplasm, and where the nuclear envelope is
+const (
+	tk2Count        = 4
+	tfaCount        = 3
+	nuclearEnvelope = 600
+	importSpeed     = 60 // Drift of an active TF toward the nuclear envelope, in units per second
+	envelopeReach   = 5  // How close to the envelope an active TF must come to meet importin
+)
+
 type TransductionLevel struct {
 	// TRANSDUCTION SPRITES
 	protoCytoBg_1     StillImage
 	cytoBg_1          Parallax
 	cytoNuc_1         Parallax
 	tk1               Kinase
-	tk2               Kinase
-	tfa               TFA
+	tk2s              [tk2Count]Kinase
+	tfas              [tfaCount]TFA
 	infoButton        InfoPage
 	otherToMenuButton Button
+	speedButton       Button
 	message           string
+	scene             *SceneGraph
+	world             *World
+	nucleus           *Compartment
+	imported          []*Particle // Active TFs, the first of which to reach the envelope is imported
 }
 
-var transductionStruct *TransductionLevel
-
 func newTransductionLevel(g *Game) {
 	if len(g.transductionSprites) == 0 {
-		transductionStruct = &TransductionLevel{
+		t := &TransductionLevel{
 			protoCytoBg_1: newStillImage("CytoBg1.png", newRect(0, 0, 1250, 750)),
-			cytoBg_1:      newParallax("ParallaxCyto1.png", newRect(100, 100, 1250, 750), 4),
-			cytoNuc_1:     newParallax("ParallaxCyto1.5.png", newRect(100, 100, 1250, 750), 3),
+			cytoBg_1:      newParallax("Signal Transduction", "ParallaxCyto1.png", newRect(100, 100, 1250, 750), 4),
+			cytoNuc_1:     newParallax("Signal Transduction", "ParallaxCyto1.5.png", newRect(100, 100, 1250, 750), 3),
+
+			tk1: newKinase("inact_TK1.png", "act_TK1.png", newRect(500, 20, 150, 150), "tk1"),
 
-			tk1: newKinase("inact_TK1.png", "act_TK1.png", newRect(500, -100, 150, 150), "tk1"),
-			tk2: newKinase("inact_TK2.png", "act_TK2.png", newRect(250, 175, 150, 150), "tk2"),
-			tfa: newTFA("inact_TFA.png", "act_TFA.png", newRect(700, 500, 150, 150), "tfa1"),
+			message: "WELCOME TO THE CYTOPLASM! \n" +
+				"Kinases only pass the signal on when they \n" +
+				"bump into each other. Speed up time to wait less!",
+		}
+		t.infoButton = infoButton
+		t.otherToMenuButton = otherToMenuButton
+		t.speedButton = newLabelButton("codonButton.png", newRect(1030, 190, 192, 106), func(g *Game) {
+			t.speedUp()
+		}, "Speed x1")
+
+		// The rest of the cascade is scattered through the cytoplasm at random
+		rng := pathwaySim.Rand()
+		scatter := func() Rectangle {
+			return newRect(float64(rng.Intn(screenWidth-150)), float64(200+rng.Intn(nuclearEnvelope-350)), 150, 150)
+		}
+		for x := range t.tk2s {
+			t.tk2s[x] = newKinase("inact_TK2.png", "act_TK2.png", scatter(), "tk2")
+		}
+		for x := range t.tfas {
+			t.tfas[x] = newTFA("inact_TFA.png", "act_TFA.png", scatter(), "tfa1")
+		}
 
-			message: 
-				"WELCOME TO THE CYTOPLASM! \n" +
-				"Click where the kinases overlap to \n" +
-				"follow the phosphorylation cascade!!",
+		g.transductionSprites = []Updatable{
+			&t.cytoBg_1, &t.cytoNuc_1, &t.tk1,
+		}
+		for x := range t.tk2s {
+			g.transductionSprites = append(g.transductionSprites, &t.tk2s[x])
+		}
+		for x := range t.tfas {
+			g.transductionSprites = append(g.transductionSprites, &t.tfas[x])
 		}
-		transductionStruct.infoButton = infoButton
-		transductionStruct.otherToMenuButton = otherToMenuButton
+		g.transductionSprites = append(g.transductionSprites, &t.otherToMenuButton, &t.speedButton, &t.infoButton)
 
-		g.transductionSprites = []GUI{
-			&transductionStruct.protoCytoBg_1, &transductionStruct.cytoBg_1,
-			&transductionStruct.cytoNuc_1, &transductionStruct.tk1, &transductionStruct.tk2,
-			&transductionStruct.tfa, &transductionStruct.otherToMenuButton, &transductionStruct.infoButton,
+		t.buildWorld()
+
+		t.scene = newSceneGraph()
+		t.scene.add(&t.protoCytoBg_1, zBackground)
+		t.scene.add(&t.cytoBg_1, zBackground)
+		t.scene.add(&t.cytoNuc_1, zBackground)
+		t.scene.add(&t.tk1, zWorld)
+		for x := range t.tk2s {
+			t.scene.add(&t.tk2s[x], zWorld)
+		}
+		for x := range t.tfas {
+			t.scene.add(&t.tfas[x], zWorld)
 		}
+		t.scene.add(DrawFunc(effects.draw), zHighlight)
+		t.scene.add(DrawFunc(func(screen *ebiten.Image) {
+			defaultFont.drawFont(screen, t.message, 75, 50, color.Black)
+		}), zText)
+		t.scene.add(&t.otherToMenuButton, zButtons)
+		t.scene.add(&t.speedButton, zButtons)
+		t.scene.add(&t.infoButton, zOverlay)
+		g.levels["Signal Transduction"] = t
+	}
+	g.stateMachine.state = g.levels["Signal Transduction"]
+}
+
+// Puts every molecule of the cascade into the physics layer. An active kinase that
+// bumps into the next molecule down the cascade phosphorylates it.
+func (t *TransductionLevel) buildWorld() {
+	t.nucleus = &Compartment{"nucleus", nuclearEnvelope, screenHeight}
+	t.world = newWorld(&Compartment{"cytoplasm", 0, nuclearEnvelope}, t.nucleus)
+
+	t.world.add("TK1", &t.tk1.rect, kinaseDiffusion).active = true
+	tk2s := map[*Particle]*Kinase{}
+	for x := range t.tk2s {
+		tk2s[t.world.add("TK2", &t.tk2s[x].rect, kinaseDiffusion)] = &t.tk2s[x]
+	}
+	tfas := map[*Particle]*TFA{}
+	for x := range t.tfas {
+		tfas[t.world.add("TFA", &t.tfas[x].rect, tfaDiffusion)] = &t.tfas[x]
 	}
-	g.stateMachine.state = transductionStruct
+
+	t.world.react("TK1", "TK2", func(enzyme, substrate *Particle) {
+		if pathwaySim.Step(sim.Input{Action: sim.Phosphorylate, Target: "TK2"}).Accepted {
+			substrate.active = true
+			tk2s[substrate].activate()
+		}
+	})
+	t.world.react("TK2", "TFA", func(enzyme, substrate *Particle) {
+		if pathwaySim.Step(sim.Input{Action: sim.Phosphorylate, Target: "TFA"}).Accepted {
+			// Phosphorylation exposes the TF's nuclear localization signal and it is carried
+			// toward the nucleus. It cannot cross the envelope by itself: importin takes it
+			// through a pore in the next stage.
+			substrate.active = true
+			substrate.drift = newVector(0, importSpeed)
+			tfas[substrate].activate()
+			t.imported = append(t.imported, substrate)
+		}
+	})
+}
+
+func (t *TransductionLevel) speedUp() {
+	t.world.speedUp()
+	t.speedButton.label = fmt.Sprintf("Speed x%d", t.world.timeScale())
 }
 
 func (t *TransductionLevel) Init(g *Game) {
@@ -58,24 +151,18 @@ func (t *TransductionLevel) Update(g *Game) {
 	for _, element := range g.transductionSprites {
 		element.update(g)
 	}
-	if t.tk1.is_clicked_on {
-		t.tk2.activate()
-		t.tk1.is_clicked_on = false
+	if input.isKeyJustPressed(ebiten.KeyT) {
+		t.speedUp()
 	}
-	if t.tk2.is_clicked_on {
-		t.tfa.activate()
-		t.tk2.is_clicked_on = false
-	}
-	if t.tfa.rect.pos.y > screenHeight {
-		ToNucleus(g)
+	t.world.update()
+	for _, p := range t.imported {
+		if p.centre().y+p.radius >= t.nucleus.top-envelopeReach {
+			ToImport(g)
+			return
+		}
 	}
 }
 
 func (t *TransductionLevel) Draw(g *Game, screen *ebiten.Image) {
-	for _, element := range g.transductionSprites {
-		if element != &t.infoButton{element.draw(screen)}
-	}
-	defaultFont.drawFont(screen, t.message, 75, 50, color.Black)
-
-	t.infoButton.draw(screen)
+	t.scene.draw(screen)
 }
@@ -1,17 +1,17 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
-	"math/rand"
 
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
-var (
-	mrna_ptr = 0
-	mrna     	[5]Template
-	protein  	[5]Transcript	
-	mRNAbases   [15]Nucleobase
+const (
+	mirnaSpeed  = 30 // MicroRNA drifting toward the 3' UTR, in units per second
+	decayedTime = 3  // Seconds the decayed transcript's yield is shown before the run ends
 )
 
 type TranslationLevel struct {
@@ -21,25 +21,33 @@ type TranslationLevel struct {
 	cytoNuc_2         Parallax
 	ribosome          Ribosome
 	mRNA			  [5]Template
+	protein           [5]Transcript
+	mRNAbases         [15]Nucleobase
 	rightTrna         tRNA
 	wrongTrna1        tRNA
 	wrongTrna2        tRNA
 	infoButton        InfoPage
 	otherToMenuButton Button
 	message           string
-}
+	scene             *SceneGraph
+	mRNAbaseNodes     [15]*Node
+	dragDrop          *DragDrop
 
-var translationStruct *TranslationLevel
+	mirna     Cargo
+	mirnaFor  *sim.MicroRNA // MicroRNA the mirna sprite is showing
+	doneTimer float64       // Seconds since the transcript decayed
+	spots     [3]float64    // Where the tRNAs wait, in the order last shuffled
+}
 
 func newTranslationLevel(g *Game) {
 	if len(g.translationSprites) == 0 {
-		translationStruct = &TranslationLevel{
+		t := &TranslationLevel{
 			protoCytoBg_2: newStillImage("CytoBg2.png", newRect(0, 0, 1250, 750)),
-			cytoBg_2:      newParallax("ParallaxCyto2.png", newRect(100, 100, 1250, 750), 4),
-			cytoNuc_2:     newParallax("ParallaxCyto2.5.png", newRect(100, 100, 1250, 750), 3),
+			spots:         choiceSpots,
+			cytoBg_2:      newParallax("Translation", "ParallaxCyto2.png", newRect(100, 100, 1250, 750), 4),
+			cytoNuc_2:     newParallax("Translation", "ParallaxCyto2.5.png", newRect(100, 100, 1250, 750), 3),
 
 			ribosome: newRibosome("ribosome.png", newRect(-200, 50, 300, 330)),
-			mRNA: mrna,
 			message: 
 				"FINALLY, BACK TO THE CYTOPLASM! \n" +
 				"Drag the tRNA with the corresponding \n" +
@@ -47,46 +55,184 @@ func newTranslationLevel(g *Game) {
 				"synthesize your protein!!!!",
 		}
 
-		translationStruct.rightTrna = newTRNA("tRNA.png", newRect(100, 450, 140, 200), mrna[0].codon, translate(mrna[0].codon))
-		randomCodon1 := randomRNACodon(translationStruct.rightTrna.codon)
-		translationStruct.wrongTrna1 = newTRNA("tRNA.png", newRect(400, 450, 140, 200), randomCodon1, translate(randomCodon1))
-		randomCodon2 := randomRNACodon(translationStruct.rightTrna.codon)
-		translationStruct.wrongTrna2 = newTRNA("tRNA.png", newRect(700, 450, 140, 200), randomCodon2, translate(randomCodon2))
-		translationStruct.infoButton = infoButton
-		translationStruct.otherToMenuButton = otherToMenuButton
-
-		g.translationSprites = []GUI{
-			&translationStruct.protoCytoBg_2, &translationStruct.cytoBg_2, &translationStruct.cytoNuc_2,
-			&translationStruct.ribosome, &translationStruct.mRNA[0],
-			&translationStruct.rightTrna, &translationStruct.wrongTrna1,
-			&translationStruct.wrongTrna2, &translationStruct.otherToMenuButton,
-			&translationStruct.infoButton, 
+		for x := 0; x < 5; x++ {
+			t.mRNA[x] = newTemplate("DNA.png", newRect(float64(100*x), 250, 150, 150), pathwaySim.MRNA[x], x)
+			t.protein[x] = newTranscript("aminoAcid.png", newRect(float64(125+(150*x)), 225, 150, 150), pathwaySim.Protein[x], false)
+		}
+		t.rightTrna = newTRNA("tRNA.png", newRect(100, 450, 140, 200), pathwaySim.MRNA[0], sim.Translate(pathwaySim.MRNA[0]))
+		randomCodon1 := pathwaySim.RandomRNACodon(t.rightTrna.codon)
+		t.wrongTrna1 = newTRNA("tRNA.png", newRect(400, 450, 140, 200), randomCodon1, sim.Translate(randomCodon1))
+		randomCodon2 := pathwaySim.RandomRNACodon(t.rightTrna.codon)
+		t.wrongTrna2 = newTRNA("tRNA.png", newRect(700, 450, 140, 200), randomCodon2, sim.Translate(randomCodon2))
+		t.mirna = newCargo("RNA0.png", newRect(1250, 600, 143, 73), 0.08, "", "miRNA")
+		t.ribosome.onCodon = t.nextCodon
+		t.infoButton = infoButton
+		t.otherToMenuButton = otherToMenuButton
+
+		g.translationSprites = []Updatable{
+			&t.cytoBg_2, &t.cytoNuc_2,
+			&t.ribosome, &t.rightTrna, &t.wrongTrna1,
+			&t.wrongTrna2, &t.otherToMenuButton,
+			&t.infoButton,
 		}
+		t.buildScene()
+		g.levels["Translation"] = t
+	}
+	g.stateMachine.state = g.levels["Translation"]
+}
+
+func (t *TranslationLevel) buildScene() {
+	t.scene = newSceneGraph()
+	t.scene.add(&t.protoCytoBg_2, zBackground)
+	t.scene.add(&t.cytoBg_2, zBackground)
+	t.scene.add(&t.cytoNuc_2, zBackground)
+	t.scene.add(&t.mRNA[0], zWorld)
+	t.scene.add(DrawFunc(t.DrawProtein), zWorld)
+	t.scene.add(&t.ribosome, zWorld)
+	for x := range t.mRNAbases {
+		t.mRNAbaseNodes[x] = t.scene.add(&t.mRNAbases[x], zWorld)
 	}
-	g.stateMachine.state = translationStruct
+	t.rightTrna.addTo(t.scene.root, zCarried)
+	t.wrongTrna1.addTo(t.scene.root, zCarried)
+	t.wrongTrna2.addTo(t.scene.root, zCarried)
+	t.scene.add(DrawFunc(t.DrawText), zText)
+	t.scene.add(&t.otherToMenuButton, zButtons)
+	t.scene.add(&t.infoButton, zOverlay)
+
+	// tRNA dropped on the ribosome is checked against the mRNA codon by the simulation.
+	// The microRNA has no target; it binds by drifting or being dropped onto the 3' UTR.
+	ribosome := newTarget(&t.ribosome.rect, func(d Draggable) bool {
+		c, ok := d.(*tRNA)
+		if !ok || !pathwaySim.Step(sim.Input{Action: sim.PlaceTRNA, Target: c.codon}).Accepted {
+			return false
+		}
+		// Peptide bond joins the new amino acid onto the chain
+		centre := rectCentre(t.ribosome.rect)
+		effects.burst("peptide", centre, centre)
+		return true
+	})
+	t.dragDrop = newDragDrop([]Draggable{&t.rightTrna, &t.wrongTrna1, &t.wrongTrna2, &t.mirna}, ribosome)
+	t.scene.add(DrawFunc(t.dragDrop.drawHover), zHighlight)
+	t.scene.add(DrawFunc(effects.draw), zHighlight)
 }
 
 func (t *TranslationLevel) Init(g *Game) {
-	mrna_ptr = 0
-	for x := 0; x < len(mRNAbases); x++ {
-		base := string(mrna[x/3].codon[x%3])
-		posX := mrna[2].rect.pos.x + (50 * x)
-		posY := mrna[2].rect.pos.y
-		mRNAbases[x] = newNucleobase(base, newRect(posX, posY, 65, 150), x, true)
+	// Uncorrected transcription errors came out of the nucleus with the mRNA
+	for x := range t.mRNA {
+		t.mRNA[x].codon = pathwaySim.MRNA[x]
+		t.protein[x].codon = pathwaySim.Protein[x]
 	}
+	for x := 0; x < len(t.mRNAbases); x++ {
+		base := string(t.mRNA[x/3].codon[x%3])
+		posX := t.mRNA[2].rect.pos.x + float64(50*x)
+		posY := t.mRNA[2].rect.pos.y
+		t.mRNAbases[x] = newNucleobase(base, newRect(posX, posY, 65, 150), x, true)
+	}
+	t.doneTimer = 0
+	t.mirnaFor = nil
+	// Until it spawns the microRNA waits off-screen, where it cannot be picked up
+	t.mirna.locked = true
 	t.ResetChoices()
 	g.state_array = g.translationSprites
 }
 
+// Moves the simulation on once the ribosome has finished with a codon
+func (t *TranslationLevel) nextCodon(g *Game) {
+	pathwaySim.Step(sim.Input{Action: sim.Advance})
+	if pathwaySim.Stage == sim.Complete {
+		ToMenu(g)
+	} else if pathwaySim.Polysome != nil && pathwaySim.Codon == 0 {
+		// Ribosome reinitiates on the same transcript until it decays
+		t.NextRound()
+	} else {
+		t.ResetChoices()
+	}
+}
+
+// Sends the lead ribosome back to the start codon for another protein
+func (t *TranslationLevel) NextRound() {
+	t.ribosome.rect.pos = newVector(-200, 50)
+	t.ResetChoices()
+}
+
+// Runs the polysome for a step and moves the microRNA toward the 3' UTR
+func (t *TranslationLevel) UpdatePolysome(g *Game) {
+	p := pathwaySim.Polysome
+	if p.Degraded {
+		t.doneTimer += fixedStep
+		if t.doneTimer > decayedTime {
+			ToMenu(g)
+		}
+		return
+	}
+	pathwaySim.Step(sim.Input{Action: sim.Elongate})
+
+	// A microRNA that has just turned up drifts in from the right
+	if p.MiRNA != nil && p.MiRNA != t.mirnaFor {
+		t.mirnaFor = p.MiRNA
+		t.mirna.name = "miRNA " + p.MiRNA.Seed
+		t.mirna.origin = newVector(1250, float64(550+pathwaySim.Rand().Intn(150)))
+		t.mirna.refuse()
+	}
+	if p.MiRNA == nil || p.MiRNA.Bound {
+		return
+	}
+	site := newRect(700, 670, 180, 40)
+	if !t.mirna.is_dragged {
+		if t.mirna.rect.pos.x > site.pos.x {
+			t.mirna.rect.pos.move(-mirnaSpeed, 0)
+		}
+		if t.mirna.rect.pos.y > site.pos.y {
+			t.mirna.rect.pos.move(0, -mirnaSpeed)
+		} else if t.mirna.rect.pos.y < site.pos.y {
+			t.mirna.rect.pos.move(0, mirnaSpeed)
+		}
+		// A microRNA whose seed does not pair with the site floats off again
+		if aabb_collision(t.mirna.rect, site) {
+			pathwaySim.Step(sim.Input{Action: sim.BindMiRNA})
+			t.mirna.locked = true
+		}
+	}
+	// Dragging a microRNA off-screen clears it before it can bind
+	if t.mirna.rect.pos.y > screenHeight || t.mirna.rect.pos.x < 0 {
+		pathwaySim.Step(sim.Input{Action: sim.ClearMiRNA})
+		t.mirna.locked = true
+	}
+}
+
+// Draws the 3' UTR site, the shrinking poly-A tail, trailing ribosomes and protein yield
+func (t *TranslationLevel) DrawPolysome(screen *ebiten.Image) {
+	p := pathwaySim.Polysome
+	defaultFont.drawFont(screen, "3' UTR "+sim.UTRSite, 620, 705, color.Black)
+	vector.DrawFilledRect(screen, 900, 690, float32(p.Tail)*300/sim.PolyATailLength, 20, color.RGBA{220, 180, 40, 255}, false)
+	defaultFont.drawFont(screen, "poly-A: "+fmt.Sprint(p.Tail), 900, 680, color.Black)
+	// Trailing ribosomes sit on the codon they are reading, above the lead ribosome
+	for _, r := range p.Ribosomes {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(0.6*t.ribosome.scaleW, 0.6*t.ribosome.scaleH)
+		op.GeoM.Translate(max(44, float64(160*r.Codon)), 120)
+		op.Filter = ebiten.FilterLinear
+		screen.DrawImage(t.ribosome.image, op)
+	}
+	if p.MiRNA != nil {
+		t.mirna.draw(screen)
+	}
+	defaultFont.drawFont(screen, "Proteins made: "+fmt.Sprint(p.Yield), 75, 200, color.RGBA{0, 100, 0, 255})
+	if p.Degraded {
+		defaultFont.drawFont(screen, "mRNA DECAYED! Final yield: "+fmt.Sprint(p.Yield), 75, 240, color.RGBA{200, 0, 0, 255})
+	} else if p.MiRNA != nil && p.MiRNA.Bound {
+		defaultFont.drawFont(screen, "miRNA bound: translation blocked!", 75, 240, color.RGBA{200, 0, 0, 255})
+	}
+}
+
 func (t *TranslationLevel) ResetChoices() {
-	curr := &mrna[mrna_ptr]
-	rand.Shuffle(len(spots), func(i, j int) {spots[i], spots[j] = spots[j], spots[i]})
-	t.rightTrna.reset(0, 450, curr.codon, translate(curr.codon))
-	randomCodon1 := randomRNACodon(t.rightTrna.codon)
-	t.wrongTrna1.reset(1, 450, randomCodon1, translate(randomCodon1))
-	randomCodon2 := randomRNACodon(t.rightTrna.codon)
-	t.wrongTrna2.reset(2, 450, randomCodon2, translate(randomCodon2))
-	reset = false
+	curr := &t.mRNA[pathwaySim.Codon]
+	pathwaySim.Rand().Shuffle(len(t.spots), func(i, j int) { t.spots[i], t.spots[j] = t.spots[j], t.spots[i] })
+	t.rightTrna.reset(t.spots[0], 450, curr.codon, sim.Translate(curr.codon))
+	randomCodon1 := pathwaySim.RandomRNACodon(t.rightTrna.codon)
+	t.wrongTrna1.reset(t.spots[1], 450, randomCodon1, sim.Translate(randomCodon1))
+	randomCodon2 := pathwaySim.RandomRNACodon(t.rightTrna.codon)
+	t.wrongTrna2.reset(t.spots[2], 450, randomCodon2, sim.Translate(randomCodon2))
 }
 
 func (t *TranslationLevel) Update(g *Game) {
@@ -95,55 +241,57 @@ func (t *TranslationLevel) Update(g *Game) {
 	t.otherToMenuButton.update(g)
 	t.infoButton.update()
 
-	curr := &mrna[mrna_ptr]
+	// Mode can only be switched before the first codon is translated
+	if input.isKeyJustPressed(ebiten.KeyP) {
+		pathwaySim.Step(sim.Input{Action: sim.TogglePolysome})
+	}
 
-	if reset {t.ResetChoices()}
+	if pathwaySim.Polysome != nil {
+		t.UpdatePolysome(g)
+	}
 
-	t.rightTrna.update(curr)
-	t.wrongTrna1.update(curr)
-	t.wrongTrna2.update(curr)
+	t.dragDrop.update()
+	for _, c := range []*tRNA{&t.rightTrna, &t.wrongTrna1, &t.wrongTrna2} {
+		c.update()
+	}
 
-	t.ribosome.update(g)
+	// Lead ribosome waits off the transcript while it cannot reinitiate
+	if p := pathwaySim.Polysome; p == nil || (!p.Parked && !p.Degraded) {
+		t.ribosome.update(g)
+	}
 }
 
 func (t *TranslationLevel) Draw(g *Game, screen *ebiten.Image) {
-	t.protoCytoBg_2.draw(screen)
-	t.cytoBg_2.draw(screen)
-	t.cytoNuc_2.draw(screen)
-
-	t.mRNA[0].draw(screen)
-
-	t.otherToMenuButton.draw(screen)
-
-	if t.ribosome.rect.pos.x >= 42 {
-		// Draw amino acids before ribosome moves without drawing amino acid for STOP.
-		for x := 0; x <= mrna_ptr; x++ {
-			if x < 4 {
-				protein[x].draw(screen)
-				codonFont.drawFont(screen, protein[x].codon, protein[x].rect.pos.x, protein[x].rect.pos.y+25, color.Black)
-			}
-		}
+	// Only the codon being read shows its bases
+	for y, node := range t.mRNAbaseNodes {
+		node.visible = y/3 == pathwaySim.Codon
 	}
+	t.scene.draw(screen)
+}
 
-	t.ribosome.draw(screen)
+// Draws the amino acids made so far, once the ribosome has reached the start codon
+func (t *TranslationLevel) DrawProtein(screen *ebiten.Image) {
+	if t.ribosome.rect.pos.x < 42 {
+		return
+	}
+	// No amino acid is drawn for STOP, or for anything after a premature one
+	for x := 0; x <= pathwaySim.Codon && x < pathwaySim.StopCodon(); x++ {
+		t.protein[x].draw(screen)
+		codonFont.drawFont(screen, t.protein[x].codon, t.protein[x].rect.pos.x, t.protein[x].rect.pos.y+25, color.Black)
+	}
+}
 
-	t.rightTrna.draw(screen)
-	t.wrongTrna1.draw(screen)
-	t.wrongTrna2.draw(screen)
+func (t *TranslationLevel) DrawText(screen *ebiten.Image) {
+	if pathwaySim.Polysome != nil {
+		t.DrawPolysome(screen)
+	} else if pathwaySim.Codon == 0 {
+		defaultFont.drawFont(screen, "Press P for polysome mode", 75, 200, color.Black)
+	}
 
-	if mrna_ptr != -1 {
-		if mrna_ptr == 0 {
-			mRNAbases[0].draw(screen)
-			mRNAbases[1].draw(screen)
-			mRNAbases[2].draw(screen)
-		} else {
-			for y := 0; y < 3; y++ {
-				mRNAbases[(mrna_ptr*3)+y].draw(screen)
-			}
-		}
+	if pathwaySim.StopCodon() < 4 {
+		// A nonsense mutation that slipped past proofreading truncates the protein
+		defaultFont.drawFont(screen, "Cut short at codon "+fmt.Sprint(pathwaySim.StopCodon()+1)+"!", 700, 230, color.RGBA{200, 0, 0, 255})
 	}
 
 	defaultFont.drawFont(screen, t.message, 75, 50, color.Black)
-
-	t.infoButton.draw(screen)
 }
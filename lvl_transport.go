@@ -0,0 +1,245 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Nuclear envelope runs across the screen between these heights, with the cytoplasm above
+const envelopeTop, envelopeBottom = 330, 370
+
+var poreSpots = [3]float64{250, 600, 950}
+
+// Seconds the released cargo stays on screen before the pathway moves on
+const transportDoneTime = 1.5
+
+type TransportLevel struct {
+	// NUCLEAR TRANSPORT SPRITES
+	cytoBg            StillImage
+	carrier           Enzyme
+	carrierHome       Vector // Where the carrier waits for cargo at the start
+	cargo             [3]Cargo
+	infoButton        InfoPage
+	otherToMenuButton Button
+	message           string
+	scene             *SceneGraph
+	carrierNode       *Node
+	cargoNodes        [3]*Node
+	pores             [3]Rectangle
+	dragDrop          *DragDrop
+
+	direction string // "import" into the nucleus or "export" out of it
+	carried   *Cargo
+	refusal   string
+	doneTimer float64 // Seconds since the cargo was released
+}
+
+func newImportLevel(g *Game) {
+	if len(g.importSprites) == 0 {
+		t := &TransportLevel{
+			cytoBg:    newStillImage("CytoBg1.png", newRect(0, 0, 1250, 750)),
+			carrier:   newEnzyme("codonButton.png", newRect(1000, 200, 192, 106), "Importin"),
+			direction: "import",
+			message: "WELCOME TO THE NUCLEAR ENVELOPE! \n" +
+				"Drag cargo to importin, then drag \n" +
+				"the complex into a nuclear pore!",
+		}
+		t.cargo = [3]Cargo{
+			newCargo("act_TFA.png", newRect(150, 190, 124, 91), 0.3, sim.Cargoes["TF"], "TF"),
+			newCargo("act_TK2.png", newRect(400, 190, 113, 118), 0.3, sim.Cargoes["TK2"], "TK2"),
+			newCargo("act_TK1.png", newRect(650, 190, 99, 106), 0.3, sim.Cargoes["TK1"], "TK1"),
+		}
+		t.infoButton = infoButton
+		t.otherToMenuButton = otherToMenuButton
+
+		g.importSprites = []Updatable{
+			&t.carrier, &t.otherToMenuButton, &t.infoButton,
+		}
+		t.buildScene()
+		g.levels["Nuclear Import"] = t
+	}
+	g.stateMachine.state = g.levels["Nuclear Import"]
+}
+
+func newExportLevel(g *Game) {
+	if len(g.exportSprites) == 0 {
+		t := &TransportLevel{
+			cytoBg:    newStillImage(nucleusBackground, newRect(0, 0, 1250, 750)),
+			carrier:   newEnzyme("codonButton.png", newRect(1000, 550, 192, 106), "Exportin"),
+			direction: "export",
+			message: "TIME TO LEAVE THE NUCLEUS! \n" +
+				"Drag cargo to exportin, then drag \n" +
+				"the complex into a nuclear pore!",
+		}
+		t.cargo = [3]Cargo{
+			newCargo("RNA4.png", newRect(150, 520, 214, 110), 0.12, sim.Cargoes["mRNA"], "mRNA"),
+			newCargo("rnaPolym.png", newRect(450, 520, 130, 129), 0.2, sim.Cargoes["RNA Pol"], "RNA Pol"),
+			newCargo("DNA.png", newRect(700, 520, 250, 53), 0.1, sim.Cargoes["DNA"], "DNA"),
+		}
+		t.infoButton = infoButton
+		t.otherToMenuButton = otherToMenuButton
+
+		g.exportSprites = []Updatable{
+			&t.carrier, &t.otherToMenuButton, &t.infoButton,
+		}
+		t.buildScene()
+		g.levels["Nuclear Export"] = t
+	}
+	g.stateMachine.state = g.levels["Nuclear Export"]
+}
+
+func (t *TransportLevel) buildScene() {
+	t.carrierHome = t.carrier.rect.pos
+	t.scene = newSceneGraph()
+	t.scene.add(&t.cytoBg, zBackground)
+	t.scene.add(DrawFunc(t.DrawEnvelope), zBackground)
+	for x := range t.cargo {
+		t.cargoNodes[x] = t.scene.add(&t.cargo[x], zWorld)
+	}
+	t.carrierNode = t.scene.add(&t.carrier, zWorld)
+	t.scene.add(DrawFunc(t.DrawText), zText)
+	t.scene.add(&t.otherToMenuButton, zButtons)
+	t.scene.add(&t.infoButton, zOverlay)
+
+	// Pores only let through cargo bound to the carrier, which only binds cargo with its signal.
+	// Pores come first so a bound complex dropped in one is not taken for the carrier.
+	var targets []DropTarget
+	for x, posX := range poreSpots {
+		t.pores[x] = newRect(posX, envelopeTop-40, 100, envelopeBottom-envelopeTop+80)
+		pore := newTarget(&t.pores[x], func(d Draggable) bool {
+			c := d.(*Cargo)
+			if !pathwaySim.Step(sim.Input{Action: sim.EnterPore, Target: c.name}).Accepted {
+				return false
+			}
+			// The complex is let into the middle of the pore and carried on through it
+			c.rect.pos.x = t.pores[x].pos.x + 50 - c.rect.width/2
+			c.locked = true
+			t.refusal = ""
+			return true
+		}).onReject(func(d Draggable) {
+			t.refusal = d.(*Cargo).name + " has no transport receptor:\nrefused entry!"
+		})
+		targets = append(targets, pore)
+	}
+	carrier := newTarget(&t.carrier.rect, func(d Draggable) bool {
+		c := d.(*Cargo)
+		if c.is_bound {
+			return true
+		}
+		if !pathwaySim.Step(sim.Input{Action: sim.BindCarrier, Target: c.name}).Accepted {
+			return false
+		}
+		c.is_bound = true
+		t.carried = c
+		t.refusal = ""
+		// The carrier rides beside its cargo from now on
+		for x := range t.cargo {
+			if &t.cargo[x] == c {
+				t.carrierNode.follow(t.cargoNodes[x], newVector(c.rect.width, 0))
+			}
+		}
+		return true
+	}).onReject(func(d Draggable) {
+		t.refusal = t.carrier.name + " only binds cargo\nwith an " + pathwaySim.Transport.Signal + "!"
+	})
+	targets = append(targets, carrier)
+	t.dragDrop = newDragDrop([]Draggable{&t.cargo[0], &t.cargo[1], &t.cargo[2]}, targets...)
+	t.scene.add(DrawFunc(t.dragDrop.drawHover), zHighlight)
+}
+
+func (t *TransportLevel) Init(g *Game) {
+	for x := range t.cargo {
+		t.cargo[x].refuse()
+		t.cargo[x].is_bound = false
+	}
+	t.scene.free(t.carrierNode)
+	t.carrier.rect.pos = t.carrierHome
+	t.carried = nil
+	t.refusal = ""
+	t.doneTimer = 0
+	if t.direction == "import" {
+		g.state_array = g.importSprites
+	} else {
+		g.state_array = g.exportSprites
+	}
+}
+
+func (t *TransportLevel) Update(g *Game) {
+	// Keep the carrier beside its cargo for the drop checks, not only when drawn
+	defer t.scene.layout()
+	t.otherToMenuButton.update(g)
+	t.infoButton.update()
+
+	transport := &pathwaySim.Transport
+	if transport.Released {
+		t.doneTimer += fixedStep
+		if t.doneTimer > transportDoneTime {
+			if t.direction == "import" {
+				ToReplication(g)
+			} else {
+				ToCyto2(g)
+			}
+		}
+		return
+	}
+
+	if transport.Side == sim.Pore {
+		// The complex passes through the pore and comes out on the far side, where the
+		// Ran there decides whether the carrier lets go of its cargo
+		pos := &t.carried.rect.pos
+		if t.direction == "import" {
+			pos.move(0, descendSpeed)
+			if pos.y > envelopeBottom {
+				pathwaySim.Step(sim.Input{Action: sim.Translocate, Target: sim.Nucleus})
+			}
+		} else {
+			pos.move(0, -descendSpeed)
+			if pos.y+t.carried.rect.height < envelopeTop {
+				pathwaySim.Step(sim.Input{Action: sim.Translocate, Target: sim.Cytoplasm})
+			}
+		}
+		return
+	}
+
+	t.dragDrop.update()
+}
+
+func (t *TransportLevel) Draw(g *Game, screen *ebiten.Image) {
+	t.scene.draw(screen)
+}
+
+// Nucleoplasm below the envelope, with gaps in the envelope for the pores
+func (t *TransportLevel) DrawEnvelope(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, envelopeBottom, float32(screenWidth), float32(screenHeight-envelopeBottom), color.RGBA{60, 20, 80, 60}, false)
+	poreStart := 0.0
+	for _, x := range poreSpots {
+		vector.DrawFilledRect(screen, float32(poreStart), envelopeTop, float32(x-poreStart), envelopeBottom-envelopeTop, color.RGBA{120, 90, 40, 255}, false)
+		vector.StrokeRect(screen, float32(x), envelopeTop-10, 100, envelopeBottom-envelopeTop+20, 4, color.RGBA{200, 160, 60, 255}, false)
+		poreStart = x + 100
+	}
+	vector.DrawFilledRect(screen, float32(poreStart), envelopeTop, float32(screenWidth-poreStart), envelopeBottom-envelopeTop, color.RGBA{120, 90, 40, 255}, false)
+	defaultFont.drawFont(screen, "Ran-GTP high", 75, 720, color.RGBA{200, 0, 0, 255})
+	defaultFont.drawFont(screen, "Ran-GDP", 1000, 320, color.RGBA{0, 0, 200, 255})
+	// Ran riding on the carrier
+	if ran := pathwaySim.Transport.Ran; ran != "" {
+		defaultFont.drawFont(screen, "Ran-"+ran, t.carrier.rect.pos.x, t.carrier.rect.pos.y-10, color.RGBA{200, 0, 0, 255})
+	}
+}
+
+func (t *TransportLevel) DrawText(screen *ebiten.Image) {
+	if pathwaySim.Transport.Released {
+		if t.direction == "import" {
+			defaultFont.drawFont(screen, "Ran-GTP released the cargo\ninto the nucleus!", 75, 200, color.Black)
+		} else {
+			defaultFont.drawFont(screen, "Ran-GTP hydrolysed: the mRNA\nis free in the cytoplasm!", 75, 400, color.Black)
+		}
+	}
+	if t.refusal != "" {
+		defaultFont.drawFont(screen, t.refusal, 75, 250, color.RGBA{200, 0, 0, 255})
+	}
+
+	defaultFont.drawFont(screen, t.message, 75, 50, color.Black)
+}
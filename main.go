@@ -2,45 +2,46 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
+	"image/color"
 	_ "image/png"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
+// Every scene is laid out in this logical space, which the camera fits to the window
 const (
-	baseScreenWidth, baseScreenHeight = 1250, 750
+	screenWidth, screenHeight = 1250, 750
 )
 
 var (
 	// GLOBAL VARIABLES
-	screenWidth, screenHeight = 1250, 750
-	maxWidth, maxHeight       = ebiten.ScreenSizeInFullscreen()
-	widthRatio                = float64(maxWidth / baseScreenWidth)
-	heightRatio               = float64(maxHeight / baseScreenHeight)
-
 	audioContext *audio.Context
 	audioPlayer  *audio.Player
 
-	err   error
-	scene string = "Main Menu"
+	err error
 
 	otherToMenuButton Button
 	info              string
 	infoButton        InfoPage
 
-	reset       bool
 	defaultFont Font
 	codonFont   Font
 
-	seedSignal int
-	template   = [5]string{}
+	// Pathway state and rules; levels only draw it and feed it player actions
+	pathwaySim     *sim.Simulation
+	prokaryoteMode bool
 
 	adenine   Nucleobase
 	thymine   Nucleobase
@@ -52,18 +53,26 @@ var (
 )
 
 type Game struct {
-	switchedScene bool
-	stateMachine  *StateMachine
-
-	state_array []GUI
-
-	menuSprites          []GUI
-	aboutSprites         []GUI
-	levSelSprites        []GUI
-	receptionSprites     []GUI
-	transductionSprites  []GUI
-	transcriptionSprites []GUI
-	translationSprites   []GUI
+	stateMachine *StateMachine
+	replay       *ReplayViewer    // Set when the game is playing back a recorded session
+	canvas       *ebiten.Image    // Logical screen that scenes draw on before the camera scales it
+	accumulator  float64          // Seconds of game time not yet run as fixed steps
+	levels       map[string]State // Levels built this run, by scene name
+
+	state_array []Updatable
+
+	menuSprites          []Updatable
+	aboutSprites         []Updatable
+	levSelSprites        []Updatable
+	receptionSprites     []Updatable
+	transductionSprites  []Updatable
+	transcriptionSprites []Updatable
+	translationSprites   []Updatable
+	replicationSprites   []Updatable
+	quorumSprites        []Updatable
+	coupledSprites       []Updatable
+	importSprites        []Updatable
+	exportSprites        []Updatable
 }
 
 func executableDir() string {
@@ -77,67 +86,9 @@ func executableDir() string {
     return dir
 }
 
-func loadFile(image string) string {
-    // Get the absolute path to the Assets/Images directory
-    imageDir := filepath.Join(executableDir(), "Assets", "Images")
-    // Construct the absolute path to the image file
-    imagepath := filepath.Join(imageDir, image)
-    // Open file
-    file, err := os.Open(imagepath)
-    if err != nil {
-        fmt.Println("Error opening file:", err)
-        return "Error"
-    }
-    defer file.Close()
-    return file.Name()
-}
-
-func loadFont(image string) string {
-    // Get the absolute path to the Assets/Images directory
-    fontDir := filepath.Join(executableDir(), "Assets", "Fonts")
-    // Construct the absolute path to the image file
-    fontpath := filepath.Join(fontDir, image)
-    // Open file
-    file, err := os.Open(fontpath)
-    if err != nil {
-        fmt.Println("Error opening file:", err)
-        return "Error"
-    }
-    defer file.Close()
-    return file.Name()
-}
-
-func loadMusic(music string) string {
-    // Get the absolute path to the Assets/Images directory
-    musicDir := filepath.Join(executableDir(), "Assets", "Music")
-    // Construct the absolute path to the image file
-    musicpath := filepath.Join(musicDir, music)
-    // Open file
-    file, err := os.Open(musicpath)
-    if err != nil {
-        fmt.Println("Error opening file:", err)
-        return "Error"
-    }
-    defer file.Close()
-    return file.Name()
-}
-
-func (g *Game) init() {
-	ebiten.SetWindowPosition(100, 0)
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeOnlyFullscreenEnabled)
-
-	defaultFont = newFont(loadFont("CourierPrime-Regular.ttf"), 32)
-	codonFont = newFont(loadFont("BlackOpsOne-Regular.ttf"), 60)
-
-	//	maxWidth, maxHeight       = g.Layout(maxWidth, maxHeight)
-
-	// 	Initialize audio context
-	audioContext = audio.NewContext(44100)
-
-	g.switchedScene = true
-
-	mp3Bytes, err := os.ReadFile(loadMusic("Signaling_of_the_Cell_MenuScreen.mp3"))
+// Creates the menu music player; the music is decoded with the menu's other assets
+func startMusic() {
+	mp3Bytes, err := assets.music("Signaling_of_the_Cell_MenuScreen.mp3")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -149,6 +100,24 @@ func (g *Game) init() {
 	if err != nil {
 		log.Fatal(err)
 	}
+}
+
+func (g *Game) init() {
+	ebiten.SetWindowPosition(100, 0)
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+
+	for _, problem := range assets.validate() {
+		log.Println("asset check:", problem)
+	}
+
+	defaultFont = newFont("CourierPrime-Regular.ttf", 32)
+	codonFont = newFont("BlackOpsOne-Regular.ttf", 60)
+
+	// 	Initialize audio context
+	audioContext = audio.NewContext(44100)
+
+	pathwaySim = sim.New(time.Now().UnixNano())
 
 	infoButton = newInfoPage("infoButton.png", "infoPage.png", newRect(850, 0, 165, 165), "btn")
 	otherToMenuButton = newButton("menuButton.png", newRect(1000, 0, 300, 200), ToMenu)
@@ -164,6 +133,9 @@ func (g *Game) init() {
 		"Main Menu": newMainMenu, "About": newAbout, "Level Selection": newLevelSelection,
 		"Signal Reception": newReceptionLevel, "Signal Transduction": newTransductionLevel,
 		"Transcription": newTranscriptionLevel, "Translation": newTranslationLevel,
+		"DNA Replication": newReplicationLevel,
+		"Quorum Sensing": newQuorumLevel, "Coupled Expression": newCoupledLevel,
+		"Nuclear Import": newImportLevel, "Nuclear Export": newExportLevel,
 	}
 
 	g.stateMachine = newStateMachine(s_map)
@@ -172,57 +144,113 @@ func (g *Game) init() {
 
 func (g *Game) Update() error {
 
-	ebiten.SetWindowTitle("CSPS - " + scene)
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-		ebiten.SetFullscreen(false)
+	if g.replay != nil {
+		g.replay.update(g)
+		return nil
 	}
 
-	g.stateMachine.update(g)
+	// Sessions are saved with F8, and when the window is closed
+	if recorder, ok := input.(*RecordingInput); ok {
+		if inpututil.IsKeyJustPressed(ebiten.KeyF8) || ebiten.IsWindowBeingClosed() {
+			if path, err := recorder.save(); err != nil {
+				fmt.Println("Error saving session:", err)
+			} else {
+				fmt.Println("Session saved to", path)
+			}
+		}
+		if ebiten.IsWindowBeingClosed() {
+			return ebiten.Termination
+		}
+	}
+
+	// Devices are read once a tick, whether this tick runs any steps or not
+	if polled, ok := input.(PolledInput); ok {
+		polled.poll()
+	}
+
+	// Run as many fixed steps as the time this tick covers, so motion is the same at any tick rate
+	g.accumulator += 1 / float64(ebiten.TPS())
+	for g.accumulator >= fixedStep-1e-9 {
+		// The loading screen holds the game still rather than running steps
+		if !g.stateMachine.ready(g) {
+			g.accumulator = 0
+			break
+		}
+		g.accumulator -= fixedStep
+		g.step()
+	}
 
 	return nil
 }
 
+// Advances the game by one fixed step
+func (g *Game) step() {
+	input.update()
+
+	ebiten.SetWindowTitle("CSPS - " + g.stateMachine.current)
+
+	if input.isKeyJustPressed(ebiten.KeyEscape) {
+		ebiten.SetFullscreen(false)
+	}
+
+	g.stateMachine.update(g)
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
 
-	if ebiten.IsFullscreen() {
-		// Use this if statement to set sizes of graphics to fullscreen scale, else normal scale.
-		if screenWidth == baseScreenWidth && screenHeight == baseScreenHeight {
-			screenWidth, screenHeight = ebiten.ScreenSizeInFullscreen()
-			g.stateMachine.Scale(g, screen)
-			defaultFont = newFont(loadFont("CourierPrime-Regular.ttf"), 32*int(heightRatio))
-			codonFont = newFont(loadFont("BlackOpsOne-Regular.ttf"), 60*int(heightRatio))
-		}
-	} else {
-		if screenWidth != baseScreenWidth && screenHeight != baseScreenHeight {
-			screenWidth, screenHeight = baseScreenWidth, baseScreenHeight
-			g.stateMachine.Scale(g, screen)
-			defaultFont = newFont(loadFont("CourierPrime-Regular.ttf"), 32)
-			codonFont = newFont(loadFont("BlackOpsOne-Regular.ttf"), 60)
-		}
+	if g.canvas == nil {
+		g.canvas = ebiten.NewImage(screenWidth, screenHeight)
 	}
+	g.canvas.Clear()
+
+	g.stateMachine.draw(g, g.canvas)
 
-	if g.switchedScene {
-		g.stateMachine.Scale(g, screen)
-		g.switchedScene = false
+	// Keyboard and gamepad steer a cursor the system does not draw
+	if live, ok := input.(*LiveInput); ok && live.virtualCursor() {
+		x_c, y_c := input.cursorPosition()
+		vector.StrokeCircle(g.canvas, float32(x_c), float32(y_c), 12, 3, color.RGBA{25, 0, 90, 255}, true)
 	}
 
-	g.stateMachine.draw(g, screen)
+	if g.replay != nil {
+		g.replay.draw(g.canvas)
+	}
 
+	camera.present(screen, g.canvas)
 }
 
+// The screen is the window's size in logical pixels, which the camera letterboxes the
+// canvas into. Scenes are drawn at 1250x750, so on HiDPI monitors the image is scaled up
+// rather than drawn at the device's resolution.
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	if ebiten.IsFullscreen() {
-		return outsideWidth, outsideHeight
-	} else {
-		return baseScreenWidth, baseScreenHeight
-	}
+	return outsideWidth, outsideHeight
 }
 
 func main() {
+	replayPath := flag.String("replay", "", "play back a recorded session file")
+	assetDir := flag.String("assets", "", "directory whose Images, Fonts and Music files replace the built-in ones")
+	flag.Parse()
+	assets.override = *assetDir
+
 	game := &Game{}
 	game.init()
 
+	if *replayPath != "" {
+		session, err := loadSession(*replayPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game.replay = newReplayViewer(session)
+		game.replay.restart(game)
+	} else {
+		// Every run is recorded so it can be reviewed later
+		input = newRecordingInput(input, pathwaySim.Seed)
+		ebiten.SetWindowClosingHandled(true)
+	}
+
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// How receptors move in the plane of the membrane, in screen units and seconds
+const (
+	membraneDiffusion = 300  // Receptors wander far more slowly in the membrane than molecules in the cytoplasm
+	raftSlowdown      = 0.15 // Fraction of their speed receptors keep inside a lipid raft
+	raftWidth         = 260
+	raftCount         = 2
+	vesicleSpeed      = 120 // Endocytosed receptors sinking into the cell
+)
+
+// A receptor adrift in the membrane. anchor is the place on the membrane the receptor
+// sways about, and moves along the membrane as it diffuses.
+type MembraneProtein struct {
+	anchor       *Vector
+	partner      *MembraneProtein // Other half of its dimer, or nil
+	internalized bool             // Pinched off into a vesicle, so no longer in the membrane
+}
+
+// A stretch of membrane thick with cholesterol, where receptors slow down and gather
+type Raft struct {
+	lo, hi float64
+}
+
+// The plasma membrane as a line receptors diffuse along. Receptors that meet pair up into
+// dimers and move together from then on; other receptors bump off each other.
+type Membrane struct {
+	proteins    []*MembraneProtein
+	rafts       []Raft
+	left, right float64
+	dimerize    func(a, b *MembraneProtein) // Called when two receptors pair up, if set
+}
+
+// A membrane running between two ends with rafts scattered along it
+func newMembrane(left, right float64) *Membrane {
+	m := &Membrane{left: left, right: right}
+	rng := pathwaySim.Rand()
+	for x := 0; x < raftCount; x++ {
+		lo := left + rng.Float64()*(right-left-raftWidth)
+		m.rafts = append(m.rafts, Raft{lo, lo + raftWidth})
+	}
+	return m
+}
+
+func (m *Membrane) add(anchor *Vector) *MembraneProtein {
+	p := &MembraneProtein{anchor: anchor}
+	m.proteins = append(m.proteins, p)
+	return p
+}
+
+// Takes a receptor, and its partner if it has one, into a vesicle
+func (m *Membrane) internalize(p *MembraneProtein) {
+	p.internalized = true
+	if p.partner != nil {
+		p.partner.internalized = true
+	}
+}
+
+func (m *Membrane) inRaft(x float64) bool {
+	for _, r := range m.rafts {
+		if x >= r.lo && x < r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// Gives every receptor in the membrane a random kick along it, then sorts out the ones
+// that have run into each other. Vesicles sink away from the membrane.
+func (m *Membrane) update() {
+	rng := pathwaySim.Rand()
+	var inside []*MembraneProtein
+	for _, p := range m.proteins {
+		if p.internalized {
+			p.anchor.move(0, vesicleSpeed)
+			continue
+		}
+		inside = append(inside, p)
+		// A dimer moves as one, led by its left half
+		if p.partner != nil && p.partner.anchor.x < p.anchor.x {
+			continue
+		}
+		diffusion := float64(membraneDiffusion)
+		if m.inRaft(p.anchor.x + receptorWidth/2) {
+			diffusion *= raftSlowdown
+		}
+		width := float64(receptorWidth)
+		if p.partner != nil {
+			diffusion /= 2
+			width *= 2
+		}
+		kick := math.Sqrt(2*diffusion*fixedStep) * rng.NormFloat64()
+		p.anchor.x = reflect(p.anchor.x+kick, m.left, m.right-width)
+		if p.partner != nil {
+			p.partner.anchor.x = p.anchor.x + receptorWidth
+		}
+	}
+
+	sort.SliceStable(inside, func(x, y int) bool { return inside[x].anchor.x < inside[y].anchor.x })
+	for x := 0; x+1 < len(inside); x++ {
+		a, b := inside[x], inside[x+1]
+		overlap := a.anchor.x + receptorWidth - b.anchor.x
+		if overlap <= 0 || a.partner == b {
+			continue
+		}
+		if a.partner == nil && b.partner == nil {
+			a.partner, b.partner = b, a
+			b.anchor.x = a.anchor.x + receptorWidth
+			if m.dimerize != nil {
+				m.dimerize(a, b)
+			}
+			continue
+		}
+		// Push the two apart, carrying their partners with them
+		shove(a, -overlap/2)
+		shove(b, overlap/2)
+	}
+}
+
+func shove(p *MembraneProtein, dx float64) {
+	p.anchor.x += dx
+	if p.partner != nil {
+		p.partner.anchor.x += dx
+	}
+}
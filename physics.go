@@ -0,0 +1,161 @@
+package main
+
+import "math"
+
+// Diffusion coefficients, in square screen units per second. Larger molecules wander more slowly.
+const (
+	kinaseDiffusion = 2400
+	tfaDiffusion    = 1600
+)
+
+// Time can run this many times faster than real time, stepping through the speeds in turn
+var timeScales = []int{1, 2, 4, 8}
+
+// A band of the screen between two walls, such as the cytoplasm between the plasma
+// membrane and the nuclear envelope
+type Compartment struct {
+	name        string
+	top, bottom float64
+}
+
+// A molecule moved by the physics layer instead of by its own update. Its sprite's rect is
+// moved directly, so the sprite is drawn wherever the molecule has wandered to.
+type Particle struct {
+	kind        string
+	rect        *Rectangle
+	radius      float64
+	diffusion   float64
+	drift       Vector // Directed velocity on top of diffusion, in units per second
+	compartment *Compartment
+	active      bool
+}
+
+// Fires when an active molecule of the enzyme kind touches an inactive one of the substrate kind
+type Reaction struct {
+	enzyme    string
+	substrate string
+	fire      func(enzyme, substrate *Particle)
+}
+
+// Molecules diffusing through compartments and the reactions their encounters trigger.
+// Random kicks are drawn from the simulation's source, so replays see the same encounters.
+type World struct {
+	particles    []*Particle
+	compartments []*Compartment
+	reactions    []Reaction
+	speed        int // Index into timeScales
+	grid         *SpatialHash
+}
+
+func newWorld(compartments ...*Compartment) *World {
+	return &World{compartments: compartments, grid: newSpatialHash()}
+}
+
+// Adds a molecule to the compartment its middle is in
+func (w *World) add(kind string, rect *Rectangle, diffusion float64) *Particle {
+	p := &Particle{
+		kind:      kind,
+		rect:      rect,
+		radius:    0.4 * (rect.width + rect.height) / 2,
+		diffusion: diffusion,
+	}
+	p.compartment = w.compartmentAt(p.centre().y)
+	w.particles = append(w.particles, p)
+	return p
+}
+
+func (w *World) react(enzyme, substrate string, fire func(enzyme, substrate *Particle)) {
+	w.reactions = append(w.reactions, Reaction{enzyme, substrate, fire})
+}
+
+func (w *World) compartmentAt(y float64) *Compartment {
+	for _, c := range w.compartments {
+		if y >= c.top && y < c.bottom {
+			return c
+		}
+	}
+	return nil
+}
+
+func (w *World) timeScale() int {
+	return timeScales[w.speed]
+}
+
+// Makes time run at the next speed, back to real time after the fastest
+func (w *World) speedUp() {
+	w.speed = (w.speed + 1) % len(timeScales)
+}
+
+// Runs as many fixed steps as the time scale asks for
+func (w *World) update() {
+	for x := 0; x < w.timeScale(); x++ {
+		w.step()
+	}
+}
+
+// Gives every molecule a random kick and its drift, keeps it inside its walls, then fires
+// the reactions of every pair that touch
+func (w *World) step() {
+	rng := pathwaySim.Rand()
+	for _, p := range w.particles {
+		// Each axis moves by a normal step with variance 2Dt
+		sigma := math.Sqrt(2 * p.diffusion * fixedStep)
+		p.rect.pos.x += sigma*rng.NormFloat64() + p.drift.x*fixedStep
+		p.rect.pos.y += sigma*rng.NormFloat64() + p.drift.y*fixedStep
+		w.confine(p)
+	}
+	w.grid.build(w.particles)
+	w.grid.pairs(func(a, b *Particle) {
+		w.collide(a, b)
+		w.collide(b, a)
+	})
+}
+
+func (p *Particle) centre() Vector {
+	return newVector(p.rect.pos.x+p.rect.width/2, p.rect.pos.y+p.rect.height/2)
+}
+
+func touches(a, b *Particle) bool {
+	ca, cb := a.centre(), b.centre()
+	dx, dy, reach := ca.x-cb.x, ca.y-cb.y, a.radius+b.radius
+	return dx*dx+dy*dy < reach*reach
+}
+
+func (w *World) collide(enzyme, substrate *Particle) {
+	if !enzyme.active || substrate.active {
+		return
+	}
+	for _, r := range w.reactions {
+		if r.enzyme == enzyme.kind && r.substrate == substrate.kind {
+			r.fire(enzyme, substrate)
+			return
+		}
+	}
+}
+
+// Bounces a molecule off the sides of the screen and the walls of its compartment
+func (w *World) confine(p *Particle) {
+	top, bottom := 0.0, float64(screenHeight)
+	if p.compartment != nil {
+		top, bottom = p.compartment.top, p.compartment.bottom
+	}
+	c := p.centre()
+	x := reflect(c.x, p.radius, screenWidth-p.radius)
+	y := reflect(c.y, top+p.radius, bottom-p.radius)
+	p.rect.pos.x += x - c.x
+	p.rect.pos.y += y - c.y
+}
+
+// Mirrors a coordinate that has passed a wall back inside it
+func reflect(v, lo, hi float64) float64 {
+	if hi <= lo {
+		return (lo + hi) / 2
+	}
+	if v < lo {
+		v = 2*lo - v
+	}
+	if v > hi {
+		v = 2*hi - v
+	}
+	return math.Max(lo, math.Min(v, hi))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+)
+
+const benchParticles = 5000
+
+// Fills the transduction compartments with small kinases and substrates spread
+// evenly over the screen, half of them active
+func benchWorld() (*World, *int) {
+	w := newWorld(&Compartment{"cytoplasm", 0, nuclearEnvelope}, &Compartment{"nucleus", nuclearEnvelope, screenHeight})
+	for x := 0; x < benchParticles; x++ {
+		rect := newRect(float64(x*37%(screenWidth-16)), float64(x*53%(screenHeight-16)), 16, 16)
+		kind := "kinase"
+		if x%2 == 1 {
+			kind = "substrate"
+		}
+		w.add(kind, &rect, kinaseDiffusion).active = kind == "kinase"
+	}
+	encounters := 0
+	w.react("kinase", "substrate", func(enzyme, substrate *Particle) { encounters++ })
+	return w, &encounters
+}
+
+// One fixed step of 5,000 molecules has to fit in a 60 TPS tick
+func BenchmarkWorldStep5000(b *testing.B) {
+	pathwaySim = sim.New(1)
+	w, _ := benchWorld()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		w.step()
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "steps/s")
+	if perStep := b.Elapsed().Seconds() / float64(b.N); perStep > sim.TickSeconds {
+		b.Fatalf("a step took %.1f ms, over the %.1f ms tick", perStep*1000, sim.TickSeconds*1000)
+	}
+}
+
+func TestWorldStep5000(t *testing.T) {
+	pathwaySim = sim.New(1)
+	w, encounters := benchWorld()
+	start := time.Now()
+	for n := 0; n < 60; n++ {
+		w.step()
+	}
+	if perStep := time.Since(start).Seconds() / 60; perStep > sim.TickSeconds {
+		t.Errorf("a step took %.1f ms, over the %.1f ms tick", perStep*1000, sim.TickSeconds*1000)
+	}
+	if *encounters == 0 {
+		t.Fatal("no molecules met in a second")
+	}
+	for _, p := range w.particles {
+		c := p.centre()
+		if c.x < 0 || c.x > screenWidth || p.compartment == nil ||
+			c.y < p.compartment.top || c.y > p.compartment.bottom {
+			t.Fatalf("%s at %v left its compartment", p.kind, c)
+		}
+	}
+}
+
+func TestSpatialHashNear(t *testing.T) {
+	pathwaySim = sim.New(1)
+	w, _ := benchWorld()
+	w.step()
+	queries := []struct {
+		pos    Vector
+		radius float64
+	}{
+		{newVector(625, 375), 40},
+		{newVector(0, 0), 100},
+		{newVector(screenWidth, screenHeight), 25},
+		{newVector(300, 600), 7},
+		{newVector(-50, 200), 80},
+		{newVector(900, 100), 0},
+	}
+	for _, q := range queries {
+		found := map[*Particle]bool{}
+		w.grid.near(q.pos, q.radius, func(p *Particle) {
+			if found[p] {
+				t.Fatalf("near %v found %s twice", q.pos, p.kind)
+			}
+			found[p] = true
+		})
+		want := 0
+		for _, p := range w.particles {
+			c := p.centre()
+			dx, dy := c.x-q.pos.x, c.y-q.pos.y
+			if dx*dx+dy*dy <= q.radius*q.radius {
+				want++
+				if !found[p] {
+					t.Fatalf("near %v within %g missed a molecule at %v", q.pos, q.radius, c)
+				}
+			}
+		}
+		if len(found) != want {
+			t.Fatalf("near %v within %g found %d molecules, want %d", q.pos, q.radius, len(found), want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Draw layers, lowest first. Nodes on the same layer are drawn in the order they were added.
+const (
+	zBackground = 0
+	zWorld      = 10
+	zHighlight  = 15 // Outline of the drop target under a dragged piece
+	zCarried    = 20 // Pieces the player drags, drawn over the world
+	zText       = 30
+	zButtons    = 40
+	zOverlay    = 50 // Info page, drawn over everything
+)
+
+// An element of a level's scene. A node that follows its parent is kept at an offset from
+// the parent's position; the others keep whatever position their own update gives them.
+// Hiding a node hides everything under it.
+type Node struct {
+	gui      Drawable
+	pos      *Vector
+	parent   *Node
+	children []*Node
+	offset   Vector
+	follows  bool
+	z        int
+	visible  bool
+}
+
+type SceneGraph struct {
+	root *Node
+}
+
+// Lets text and shapes a level draws itself take part in the draw order
+type DrawFunc func(screen *ebiten.Image)
+
+func (f DrawFunc) draw(screen *ebiten.Image) {
+	f(screen)
+}
+
+// Position of a sprite, for nodes that are followed or follow another
+func (s *Sprite) position() *Vector {
+	return &s.rect.pos
+}
+
+func newSceneGraph() *SceneGraph {
+	return &SceneGraph{root: &Node{visible: true}}
+}
+
+// Adds an element at the top of the scene
+func (s *SceneGraph) add(gui Drawable, z int) *Node {
+	return s.root.add(gui, z)
+}
+
+// Adds a child that moves by itself until it is told to follow
+func (n *Node) add(gui Drawable, z int) *Node {
+	child := &Node{gui: gui, parent: n, z: z, visible: true}
+	if p, ok := gui.(interface{ position() *Vector }); ok {
+		child.pos = p.position()
+	}
+	n.children = append(n.children, child)
+	return child
+}
+
+// Adds an empty child that only groups others, e.g. to hide them together
+func (n *Node) group(z int) *Node {
+	return n.add(nil, z)
+}
+
+// Adds a child kept at an offset from this node
+func (n *Node) attach(gui Drawable, offset Vector, z int) *Node {
+	child := n.add(gui, z)
+	child.follows, child.offset = true, offset
+	return child
+}
+
+// Moves the node under a new parent and keeps it at an offset from it
+func (n *Node) follow(parent *Node, offset Vector) {
+	if n.parent != parent {
+		siblings := n.parent.children
+		for x, c := range siblings {
+			if c == n {
+				n.parent.children = append(siblings[:x:x], siblings[x+1:]...)
+				break
+			}
+		}
+		parent.children = append(parent.children, n)
+		n.parent = parent
+	}
+	n.follows, n.offset = true, offset
+}
+
+// Returns a following node to the top of the scene, where it moves by itself again
+func (s *SceneGraph) free(n *Node) {
+	n.follow(s.root, Vector{})
+	n.follows = false
+}
+
+// Places every following node relative to its parent, parents first
+func (n *Node) layout() {
+	for _, c := range n.children {
+		if c.follows && c.pos != nil && n.pos != nil {
+			*c.pos = newVector(n.pos.x+c.offset.x, n.pos.y+c.offset.y)
+		}
+		c.layout()
+	}
+}
+
+func (n *Node) collect(nodes []*Node) []*Node {
+	for _, c := range n.children {
+		if c.visible {
+			nodes = append(nodes, c)
+			nodes = c.collect(nodes)
+		}
+	}
+	return nodes
+}
+
+func (s *SceneGraph) layout() {
+	s.root.layout()
+}
+
+// Lays the scene out and draws its visible nodes from the lowest layer up
+func (s *SceneGraph) draw(screen *ebiten.Image) {
+	s.layout()
+	nodes := s.root.collect(nil)
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].z < nodes[j].z })
+	for _, n := range nodes {
+		if n.gui != nil {
+			n.gui.draw(screen)
+		}
+	}
+}
@@ -1,44 +1,71 @@
 package main
 
-import (
-	"fmt"
-	"math/rand"
-)
+// In prokaryote mode, quorum sensing replaces reception and transduction, and
+// coupled transcription-translation replaces the nucleus and cytoplasm stages
 
 func ToPlasma(g *Game) {
-	scene = "Signal Reception"
-	g.stateMachine.changeState(g, scene)
+	if prokaryoteMode {
+		ToQuorum(g)
+		return
+	}
+	g.stateMachine.changeState(g, "Signal Reception")
 }
 
 func ToMenu(g *Game) {
 	g.reset()
-	scene = "Main Menu"
-	g.stateMachine.changeState(g, scene)
+	g.stateMachine.changeState(g, "Main Menu")
 }
 
 func ToCyto1(g *Game) {
-	scene = "Signal Transduction"
-	g.stateMachine.changeState(g, scene)
+	if prokaryoteMode {
+		ToQuorum(g)
+		return
+	}
+	g.stateMachine.changeState(g, "Signal Transduction")
 }
 
 func ToNucleus(g *Game) {
-	scene = "Transcription"
-	g.stateMachine.changeState(g, scene)
+	if prokaryoteMode {
+		ToCoupled(g)
+		return
+	}
+	g.stateMachine.changeState(g, "Transcription")
 }
 
 func ToCyto2(g *Game) {
-	scene = "Translation"
-	g.stateMachine.changeState(g, scene)
+	if prokaryoteMode {
+		ToCoupled(g)
+		return
+	}
+	g.stateMachine.changeState(g, "Translation")
+}
+
+func ToReplication(g *Game) {
+	g.stateMachine.changeState(g, "DNA Replication")
+}
+
+func ToImport(g *Game) {
+	g.stateMachine.changeState(g, "Nuclear Import")
+}
+
+func ToExport(g *Game) {
+	g.stateMachine.changeState(g, "Nuclear Export")
+}
+
+func ToQuorum(g *Game) {
+	g.stateMachine.changeState(g, "Quorum Sensing")
+}
+
+func ToCoupled(g *Game) {
+	g.stateMachine.changeState(g, "Coupled Expression")
 }
 
 func ToLevelSelect(g *Game) {
-	scene = "Level Selection"
-	g.stateMachine.changeState(g, scene)
+	g.stateMachine.changeState(g, "Level Selection")
 }
 
 func ToAbout(g *Game) {
-	scene = "About"
-	g.stateMachine.changeState(g, scene)
+	g.stateMachine.changeState(g, "About")
 }
 
 func (g *Game) reset() {
@@ -50,34 +77,13 @@ func (g *Game) reset() {
 	g.transductionSprites = nil
 	g.transcriptionSprites = nil
 	g.translationSprites = nil
+	g.replicationSprites = nil
+	g.quorumSprites = nil
+	g.coupledSprites = nil
+	g.importSprites = nil
+	g.exportSprites = nil
+	g.levels = map[string]State{}
 
-	// Set seed signal to random integer
-	seedSignal = rand.Intn(4) + 1
-
-	// Set template to random codons
-	switch seedSignal {
-	case 1:
-		template = [5]string{"TAC", randomDNACodon(), randomDNACodon(), randomDNACodon(), "ACT"}
-	case 2:
-		template = [5]string{"TAC", randomDNACodon(), randomDNACodon(), randomDNACodon(), "ATT"}
-	case 3:
-		template = [5]string{"TAC", randomDNACodon(), randomDNACodon(), randomDNACodon(), "ATC"}
-	case 4:
-		template = [5]string{"TAC", randomDNACodon(), randomDNACodon(), randomDNACodon(), "ATT"}
-	}
-
-	// Set dna, rna, and proteins to random codons
-	for x := 0; x < 5; x++ {
-		dna[x] = newTemplate("DNA.png", newRect(200*x, 400, 150, 150), template[x], x)
-	}
-	for x := 0; x < 5; x++ {
-		rna[x] = newTranscript("RNA"+fmt.Sprint(x)+".png", newRect((100*x)-0, 0, 150, 150), transcribe(template[x]), true)
-	}
-
-	for x := 0; x < 5; x++ {
-		mrna[x] = newTemplate("DNA.png", newRect(100*x, 250, 150, 150), transcribe(dna[x].codon), x)
-	}
-	for x := 0; x < 5; x++ {
-		protein[x] = newTranscript("aminoAcid.png", newRect(125+(150*x), 225, 150, 150), translate(mrna[x].codon), false)
-	}
+	// Start a new run of the pathway
+	pathwaySim.Reset()
 }
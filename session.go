@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Session files start with this, followed by a format version byte
+const sessionMagic = "CSPS"
+const sessionVersion = 1
+
+// Everything needed to play a run back: the seed of the random source and every input change.
+// Each event also stores how many random draws had been made when its tick began.
+type Session struct {
+	seed   int64
+	ticks  int
+	draws  int
+	events []InputEvent
+}
+
+// Records the input of the source it wraps, only storing ticks where something changed
+type RecordingInput struct {
+	source  InputSource
+	session *Session
+	pending InputEvent
+	last    InputEvent
+	tick    int
+}
+
+func newRecordingInput(source InputSource, seed int64) *RecordingInput {
+	return &RecordingInput{
+		source:  source,
+		session: &Session{seed: seed},
+		last:    InputEvent{tick: -1},
+	}
+}
+
+// Stores the previous tick's event if the pointer moved, the button changed or a key was pressed
+func (r *RecordingInput) flush() {
+	p := r.pending
+	if r.last.tick == -1 || p.x != r.last.x || p.y != r.last.y || p.pressed != r.last.pressed || len(p.keys) > 0 {
+		r.session.events = append(r.session.events, p)
+		r.last = p
+	}
+	// Keys are only stored once, even if the event is flushed again by a save
+	r.pending.keys = nil
+}
+
+func (r *RecordingInput) update() {
+	if r.tick > 0 {
+		r.flush()
+	}
+	r.source.update()
+	x, y := r.source.cursorPosition()
+	r.pending = InputEvent{tick: r.tick, x: x, y: y, pressed: r.source.isPressed(), draws: pathwaySim.Draws()}
+	r.tick++
+	r.session.ticks = r.tick
+}
+
+func (r *RecordingInput) poll() {
+	if polled, ok := r.source.(PolledInput); ok {
+		polled.poll()
+	}
+}
+
+func (r *RecordingInput) cursorPosition() (int, int) {
+	return r.source.cursorPosition()
+}
+
+func (r *RecordingInput) isPressed() bool {
+	return r.source.isPressed()
+}
+
+func (r *RecordingInput) isJustPressed() bool {
+	return r.source.isJustPressed()
+}
+
+func (r *RecordingInput) isJustReleased() bool {
+	return r.source.isJustReleased()
+}
+
+// Keys are recorded when a level asks for them, since those are the only keys that change the run
+func (r *RecordingInput) isKeyJustPressed(key ebiten.Key) bool {
+	pressed := r.source.isKeyJustPressed(key)
+	if pressed && !r.pending.hasKey(key) {
+		r.pending.keys = append(r.pending.keys, key)
+	}
+	return pressed
+}
+
+func (e InputEvent) hasKey(key ebiten.Key) bool {
+	for _, k := range e.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes the session so far to the Sessions folder next to the executable
+func (r *RecordingInput) save() (string, error) {
+	r.flush()
+	r.session.draws = pathwaySim.Draws()
+	dir := filepath.Join(executableDir(), "Sessions")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "session-"+time.Now().Format("20060102-150405")+".csps")
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return path, r.session.write(file)
+}
+
+// Events are stored as varints relative to the event before them, which keeps a
+// session of several minutes to a few kilobytes
+func (s *Session) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, binary.MaxVarintLen64)
+	putInt := func(v int64) {
+		n := binary.PutVarint(buf, v)
+		bw.Write(buf[:n])
+	}
+	bw.WriteString(sessionMagic)
+	bw.WriteByte(sessionVersion)
+	putInt(s.seed)
+	putInt(int64(s.ticks))
+	putInt(int64(s.draws))
+	putInt(int64(len(s.events)))
+	prev := InputEvent{}
+	for _, e := range s.events {
+		putInt(int64(e.tick - prev.tick))
+		putInt(int64(e.x - prev.x))
+		putInt(int64(e.y - prev.y))
+		putInt(int64(e.draws - prev.draws))
+		flags := int64(0)
+		if e.pressed {
+			flags = 1
+		}
+		putInt(flags)
+		putInt(int64(len(e.keys)))
+		for _, k := range e.keys {
+			putInt(int64(k))
+		}
+		prev = e
+	}
+	return bw.Flush()
+}
+
+func loadSession(path string) (*Session, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	br := bufio.NewReader(file)
+
+	header := make([]byte, len(sessionMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(sessionMagic)]) != sessionMagic || header[len(sessionMagic)] != sessionVersion {
+		return nil, errors.New(path + " is not a session recording")
+	}
+	var readErr error
+	getInt := func() int64 {
+		v, err := binary.ReadVarint(br)
+		if err != nil && readErr == nil {
+			readErr = err
+		}
+		return v
+	}
+
+	s := &Session{seed: getInt(), ticks: int(getInt()), draws: int(getInt())}
+	count := int(getInt())
+	prev := InputEvent{}
+	for x := 0; x < count && readErr == nil; x++ {
+		e := InputEvent{
+			tick:  prev.tick + int(getInt()),
+			x:     prev.x + int(getInt()),
+			y:     prev.y + int(getInt()),
+			draws: prev.draws + int(getInt()),
+		}
+		e.pressed = getInt() == 1
+		for k := getInt(); k > 0; k-- {
+			e.keys = append(e.keys, ebiten.Key(getInt()))
+		}
+		s.events = append(s.events, e)
+		prev = e
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, readErr)
+	}
+	return s, nil
+}
+
+// Plays a session back through the game, with play, pause, seek and speed controls
+type ReplayViewer struct {
+	session  *Session
+	script   *ScriptedInput
+	tick     int
+	playing  bool
+	speed    float64
+	progress float64
+	diverged int // First tick where the random draws did not match the recording, or -1
+}
+
+var replaySpeeds = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+// Seconds skipped by one press of a seek key
+const replaySeekStep = 5
+
+func newReplayViewer(session *Session) *ReplayViewer {
+	return &ReplayViewer{session: session, playing: true, speed: 1, diverged: -1}
+}
+
+// Starts the run over from the recorded seed; returning to the menu rebuilds every level
+func (r *ReplayViewer) restart(g *Game) {
+	pathwaySim = sim.New(r.session.seed)
+	r.script = newScriptedInput(r.session.events...)
+	input = r.script
+	r.tick = 0
+	r.diverged = -1
+	prokaryoteMode = false
+	ToMenu(g)
+}
+
+// Advances the recorded run by one game tick, first waiting for any scene still loading
+func (r *ReplayViewer) step(g *Game) {
+	g.stateMachine.waitReady(g)
+	next := r.script.next
+	if next < len(r.session.events) && r.session.events[next].tick == r.tick {
+		if r.diverged == -1 && r.session.events[next].draws != pathwaySim.Draws() {
+			r.diverged = r.tick
+		}
+	}
+	g.step()
+	r.tick++
+}
+
+// Plays the run forward to a tick, restarting first if the tick has already passed
+func (r *ReplayViewer) seek(g *Game, tick int) {
+	tick = max(0, min(tick, r.session.ticks))
+	if tick < r.tick {
+		r.restart(g)
+	}
+	for r.tick < tick {
+		r.step(g)
+	}
+}
+
+func (r *ReplayViewer) speedIndex() int {
+	for x, s := range replaySpeeds {
+		if s == r.speed {
+			return x
+		}
+	}
+	return 2
+}
+
+// Viewer controls are read straight from the keyboard, since the game itself is reading the script
+func (r *ReplayViewer) update(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		r.playing = !r.playing
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		r.seek(g, r.tick+replaySeekStep*int(1/fixedStep))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		r.seek(g, r.tick-replaySeekStep*int(1/fixedStep))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyHome) {
+		r.seek(g, 0)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		r.speed = replaySpeeds[min(r.speedIndex()+1, len(replaySpeeds)-1)]
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		r.speed = replaySpeeds[max(r.speedIndex()-1, 0)]
+	}
+	// Clicking the progress bar seeks to that point in the run
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		x_c, y_c := camera.toLogical(ebiten.CursorPosition())
+		if y_c >= screenHeight-40 {
+			r.seek(g, r.session.ticks*x_c/screenWidth)
+		}
+	}
+
+	if !r.playing || r.tick >= r.session.ticks || !g.stateMachine.ready(g) {
+		return
+	}
+	// Recorded ticks are fixed steps, so play them at the fixed step rate whatever the tick rate
+	r.progress += r.speed / (fixedStep * float64(ebiten.TPS()))
+	for r.progress >= 1 && r.tick < r.session.ticks {
+		r.progress--
+		r.step(g)
+	}
+}
+
+func (r *ReplayViewer) draw(screen *ebiten.Image) {
+	barY := float32(screenHeight - 40)
+	vector.DrawFilledRect(screen, 0, barY, screenWidth, 40, color.RGBA{0, 0, 0, 180}, false)
+	if r.session.ticks > 0 {
+		vector.DrawFilledRect(screen, 0, barY, float32(screenWidth*r.tick/r.session.ticks), 6, color.RGBA{220, 75, 100, 255}, false)
+	}
+	status := "PLAY"
+	if !r.playing {
+		status = "PAUSE"
+	}
+	steps := int(1 / fixedStep)
+	defaultFont.drawFont(screen, fmt.Sprintf("REPLAY %s %gx  %d:%02d / %d:%02d  wrong codons: %d",
+		status, r.speed, r.tick/steps/60, r.tick/steps%60, r.session.ticks/steps/60, r.session.ticks/steps%60, pathwaySim.WrongCodons),
+		20, screenHeight-10, color.White)
+	// Recorded pointer, so the viewer can see what the player was pointing at
+	x_c, y_c := r.script.cursorPosition()
+	clr := color.RGBA{220, 75, 100, 255}
+	if r.script.isPressed() {
+		vector.DrawFilledCircle(screen, float32(x_c), float32(y_c), 10, clr, true)
+	} else {
+		vector.StrokeCircle(screen, float32(x_c), float32(y_c), 12, 3, clr, true)
+	}
+	if r.diverged != -1 {
+		defaultFont.drawFont(screen, fmt.Sprintf("Replay diverged at tick %d!", r.diverged), 20, screenHeight-50, color.RGBA{200, 0, 0, 255})
+	}
+}
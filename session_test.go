@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+// Enters transcription and returns where its codon choices wait after two rounds
+func transcriptionChoices(t *testing.T, g *Game) [3]float64 {
+	t.Helper()
+	ToNucleus(g)
+	g.stateMachine.waitReady(g)
+	level, ok := g.stateMachine.state.(*TranscriptionLevel)
+	if !ok {
+		t.Fatalf("entered %q, want Transcription", g.stateMachine.current)
+	}
+	level.ResetChoices()
+	return [3]float64{level.rightChoice.origin.x, level.wrongChoice1.origin.x, level.wrongChoice2.origin.x}
+}
+
+func TestReplaySeekBackRepeatsRun(t *testing.T) {
+	defer func(live InputSource) { input = live }(input)
+
+	g := &Game{}
+	g.init()
+	g.replay = newReplayViewer(&Session{seed: 5, ticks: 120})
+	g.replay.restart(g)
+
+	g.replay.seek(g, 60)
+	first := transcriptionChoices(t, g)
+	draws := pathwaySim.Draws()
+	// Another round shuffles the choices again before the viewer seeks back
+	transcriptionChoices(t, g)
+
+	g.replay.seek(g, 0)
+	g.replay.seek(g, 60)
+	if again := transcriptionChoices(t, g); again != first {
+		t.Fatalf("choices wait at %v after seeking back, want %v as on the first pass", again, first)
+	}
+	if pathwaySim.Draws() != draws {
+		t.Fatalf("replay made %d random draws after seeking back, want %d", pathwaySim.Draws(), draws)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Sizes of the signal and receptor images drawn for each run's generated shapes
+const (
+	ligandSize                     = 2*sim.MaxRadius + 8
+	receptorWidth, receptorHeight  = 130, 300
+	receptorHeadHeight             = 110
+	pocketCentreX, pocketCentreY   = receptorWidth / 2, 55 // Middle of the pocket, from the receptor's corner
+	receptorStalkX, receptorStalkW = 45, 40
+)
+
+// Single white pixel that filled shapes are coloured from
+var whitePixel = func() *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.White)
+	return img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+}()
+
+// Fills a convex outline as a fan of triangles from its first corner
+func fillPolygon(dst *ebiten.Image, p sim.Polygon, clr color.Color) {
+	r, g, b, a := clr.RGBA()
+	vertices := make([]ebiten.Vertex, len(p))
+	for x, pt := range p {
+		vertices[x] = ebiten.Vertex{
+			DstX: float32(pt.X), DstY: float32(pt.Y), SrcX: 1, SrcY: 1,
+			ColorR: float32(r) / 0xffff, ColorG: float32(g) / 0xffff, ColorB: float32(b) / 0xffff, ColorA: float32(a) / 0xffff,
+		}
+	}
+	var indices []uint16
+	for x := 1; x+1 < len(p); x++ {
+		indices = append(indices, 0, uint16(x), uint16(x+1))
+	}
+	dst.DrawTriangles(vertices, indices, whitePixel, &ebiten.DrawTrianglesOptions{AntiAlias: true})
+}
+
+func strokePolygon(screen *ebiten.Image, p sim.Polygon, width float32, clr color.Color) {
+	for x := range p {
+		a, b := p[x], p[(x+1)%len(p)]
+		vector.StrokeLine(screen, float32(a.X), float32(a.Y), float32(b.X), float32(b.Y), width, clr, true)
+	}
+}
+
+// Draws a signal cut to a binding site, centred in a ligandSize square
+func ligandImage(shape sim.Polygon) *ebiten.Image {
+	img := ebiten.NewImage(ligandSize, ligandSize)
+	outline := shape.Translate(ligandSize/2, ligandSize/2)
+	fillPolygon(img, outline, color.RGBA{230, 120, 40, 255})
+	strokePolygon(img, outline, 3, color.RGBA{120, 50, 10, 255})
+	return img
+}
+
+// Draws a receptor with a pocket in its head, before and after a signal binds it
+func receptorImages(pocket sim.Polygon) (*ebiten.Image, *ebiten.Image) {
+	draw := func(body color.RGBA) *ebiten.Image {
+		img := ebiten.NewImage(receptorWidth, receptorHeight)
+		vector.DrawFilledRect(img, receptorStalkX, receptorHeadHeight, receptorStalkW, receptorHeight-receptorHeadHeight, body, true)
+		vector.DrawFilledRect(img, 0, 0, receptorWidth, receptorHeadHeight, body, true)
+		outline := pocket.Translate(pocketCentreX, pocketCentreY)
+		fillPolygon(img, outline, color.RGBA{30, 25, 60, 255})
+		strokePolygon(img, outline, 3, color.RGBA{240, 240, 255, 255})
+		return img
+	}
+	return draw(color.RGBA{90, 110, 200, 255}), draw(color.RGBA{70, 190, 120, 255})
+}
+
+// Draws the replication enzymes, each in the shape that hints at what it does
+func enzymeImage(name string) *ebiten.Image {
+	switch name {
+	case "Helicase":
+		// Ring of six subunits that the DNA strand is threaded through
+		img := ebiten.NewImage(140, 140)
+		for x := 0; x < 6; x++ {
+			angle := float64(x) * math.Pi / 3
+			vector.DrawFilledCircle(img, float32(70+45*math.Cos(angle)), float32(70+45*math.Sin(angle)), 24, color.RGBA{70, 150, 210, 255}, true)
+		}
+		vector.StrokeCircle(img, 70, 70, 45, 3, color.RGBA{20, 60, 110, 255}, true)
+		return img
+	case "Primase":
+		// Small enzyme trailing the short RNA primer it lays down
+		img := ebiten.NewImage(120, 90)
+		vector.DrawFilledCircle(img, 45, 45, 40, color.RGBA{230, 170, 60, 255}, true)
+		vector.StrokeLine(img, 80, 60, 118, 60, 8, color.RGBA{200, 0, 0, 255}, true)
+		return img
+	case "DNA Pol":
+		// Hand-shaped polymerase: palm, with thumb and fingers closing round the template
+		img := ebiten.NewImage(160, 140)
+		body := color.RGBA{120, 80, 180, 255}
+		vector.DrawFilledCircle(img, 80, 85, 50, body, true)
+		vector.DrawFilledCircle(img, 35, 40, 30, body, true)
+		vector.DrawFilledCircle(img, 125, 40, 30, body, true)
+		vector.DrawFilledCircle(img, 80, 60, 18, color.RGBA{50, 20, 80, 255}, true)
+		return img
+	default:
+		// Ligase wraps round the nicked DNA, carrying the AMP that powers the seal
+		img := ebiten.NewImage(130, 130)
+		vector.StrokeCircle(img, 65, 65, 45, 22, color.RGBA{90, 170, 90, 255}, true)
+		vector.DrawFilledCircle(img, 65, 65, 14, color.RGBA{230, 200, 40, 255}, true)
+		return img
+	}
+}
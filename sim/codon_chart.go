@@ -1,11 +1,10 @@
-package main
+package sim
 
 import (
-	"math/rand"
 	"strings"
 )
 
-var codonChart = map[string]string{
+var CodonChart = map[string]string{
 	"UUU": "Phe", "UUC": "Phe", "UUA": "Leu", "UUG": "Leu",
 	"CUU": "Leu", "CUC": "Leu", "CUA": "Leu", "CUG": "Leu",
 	"AUU": "Ile", "AUC": "Ile", "AUA": "Ile", "AUG": "Met",
@@ -27,7 +26,7 @@ var codonChart = map[string]string{
 	"GGU": "Gly", "GGC": "Gly", "GGA": "Gly", "GGG": "Gly",
 }
 
-func transcribe(codon string) string {
+func Transcribe(codon string) string {
 	transcription := []string{}
 	var n string
 	for i := 0; i < len(codon); i++ {
@@ -49,14 +48,31 @@ func transcribe(codon string) string {
 	return result
 }
 
-func translate(codon string) string {
-	result := codonChart[codon]
+// Complementary DNA codon for a DNA template codon (T instead of U)
+func Replicate(codon string) string {
+	replication := []string{}
+	for i := 0; i < len(codon); i++ {
+		switch string(codon[i]) {
+		case "A":
+			replication = append(replication, "T")
+		case "T":
+			replication = append(replication, "A")
+		case "G":
+			replication = append(replication, "C")
+		case "C":
+			replication = append(replication, "G")
+		}
+	}
+	return strings.Join(replication, "")
+}
+
+func Translate(codon string) string {
+	result := CodonChart[codon]
 	return result
 }
 
-func randomBase(nuclAcid string) string {
-	seedSignal = rand.Intn(4) + 1
-	switch seedSignal {
+func (s *Simulation) RandomBase(nuclAcid string) string {
+	switch s.rng.Intn(4) + 1 {
 	case 1:
 		return "A"
 	case 2:
@@ -74,32 +90,59 @@ func randomBase(nuclAcid string) string {
 	}
 }
 
-func randomRNACodon(exception string) string {
+// Copies codon with one base swapped for a wrong base at the given error rate.
+// Returns the copied codon and the index of the misincorporated base, or -1 if none.
+func (s *Simulation) misincorporate(codon string, rate float64) (string, int) {
+	if s.rng.Float64() >= rate {
+		return codon, -1
+	}
+	index := s.rng.Intn(len(codon))
+	wrongBase := s.RandomBase("RNA")
+	for wrongBase == string(codon[index]) {
+		wrongBase = s.RandomBase("RNA")
+	}
+	return codon[:index] + wrongBase + codon[index+1:], index
+}
+
+func (s *Simulation) RandomRNACodon(exception string) string {
 	randCodon := ""
 	for x := 0; x < 3; x++ {
-		randCodon += randomBase("RNA")
+		randCodon += s.RandomBase("RNA")
 	}
 	if randCodon != exception {
 		return randCodon
 	} else {
-		return randomRNACodon(exception)
+		return s.RandomRNACodon(exception)
 	}
 }
 
-func randomDNACodon() string {
+// Random DNA codon for a wrong choice, never equal to the exception
+func (s *Simulation) RandomDNAChoice(exception string) string {
+	randCodon := ""
+	for x := 0; x < 3; x++ {
+		randCodon += s.RandomBase("DNA")
+	}
+	if randCodon != exception {
+		return randCodon
+	} else {
+		return s.RandomDNAChoice(exception)
+	}
+}
+
+func (s *Simulation) RandomDNACodon() string {
 	exceptions := []string{"ATC", "ATT", "ACT"}
 	randCodon := ""
 	for x := 0; x < 3; x++ {
-		randCodon += randomBase("DNA")
+		randCodon += s.RandomBase("DNA")
 	}
-	if !contains(exceptions, randCodon) {
+	if !Contains(exceptions, randCodon) {
 		return randCodon
 	} else {
-		return randomDNACodon()
+		return s.RandomDNACodon()
 	}
 }
 
-func contains(list []string, T any) bool {
+func Contains(list []string, T any) bool {
 	for index := 0; index < len(list); index++ {
 		if list[index] == T {
 			return true
@@ -107,4 +150,3 @@ func contains(list []string, T any) bool {
 	}
 	return false
 }
-
@@ -0,0 +1,57 @@
+package sim
+
+import "math"
+
+// How binding strength follows from shape. A ligand's affinity for a pocket runs from 0, no
+// fit at all, to 1, a perfect fit. Kd rises tenfold for every kdDecades-th of fit lost, and
+// since every signal reaches a receptor at the same on rate the off rate rises with it.
+const (
+	affinityScale = 12  // Root mean square gap between outlines, in screen units, that leaves no affinity
+	MinAffinity   = 0.3 // Weaker fits do not bind at all
+	decoyAffinity = 0.6 // Other receptors never fit a ligand this well at any angle
+	kdBest        = 1.0 // Kd of a perfect fit, in nM
+	kdDecades     = 6   // Decades Kd spans from a perfect fit to no fit
+	onRate        = 1e7 // Association rate, per molar per second
+	profileRays   = 72  // Directions the two outlines are compared in
+	dimerBoost    = 2   // A bound receptor paired with another cross-phosphorylates, activating TK1 this much faster
+	ligandConc    = 10  // Concentration of the signal around the cell, in nM
+
+	ActivationThreshold = 0.8 // TK1 activation that releases it into the cascade
+)
+
+// How well a ligand held at an angle fills a pocket, from 0 to 1, by comparing how far
+// each outline reaches from its middle all the way round
+func Affinity(ligand, pocket Polygon, angle float64) float64 {
+	turned := ligand.Rotate(angle)
+	sum := 0.0
+	for x := 0; x < profileRays; x++ {
+		direction := 2 * math.Pi * float64(x) / profileRays
+		gap := turned.radius(direction) - pocket.radius(direction)
+		sum += gap * gap
+	}
+	rms := math.Sqrt(sum / profileRays)
+	return math.Max(0, 1-rms/affinityScale)
+}
+
+// Dissociation constant of a fit, in nM
+func Kd(affinity float64) float64 {
+	return kdBest * math.Pow(10, kdDecades*(1-affinity))
+}
+
+// Chance per second that a bound signal falls off its receptor
+func OffRate(affinity float64) float64 {
+	return onRate * Kd(affinity) * 1e-9
+}
+
+// Share of the time a receptor holds the signal once binding and unbinding balance. TK1
+// activation settles at this level, so only fits with a Kd well under the signal's
+// concentration ever reach ActivationThreshold.
+func Occupancy(affinity float64) float64 {
+	return ligandConc / (ligandConc + Kd(affinity))
+}
+
+// How quickly TK1 activation closes on its settled level, per second. Weak fits barely
+// start before they fall off.
+func ActivationRate(affinity float64) float64 {
+	return math.Pow(affinity, 4)
+}
@@ -0,0 +1,66 @@
+package sim
+
+import "testing"
+
+func TestKdFallsAsAffinityRises(t *testing.T) {
+	tests := []struct {
+		affinity, kd float64
+	}{
+		{0, kdBest * 1e6},
+		{MinAffinity, kdBest * 1e4 * 1.58489319246111},
+		{0.5, kdBest * 1e3},
+		{decoyAffinity, kdBest * 251.188643150958},
+		{1, kdBest},
+	}
+	last := 0.0
+	for x, tt := range tests {
+		kd := Kd(tt.affinity)
+		if diff := kd/tt.kd - 1; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Kd(%g) = %g nM, want %g", tt.affinity, kd, tt.kd)
+		}
+		if x > 0 && kd >= last {
+			t.Errorf("Kd(%g) = %g nM is not below Kd(%g) = %g nM", tt.affinity, kd, tests[x-1].affinity, last)
+		}
+		if off := OffRate(tt.affinity); x > 0 && off >= OffRate(tests[x-1].affinity) {
+			t.Errorf("OffRate(%g) = %g/s did not fall with Kd", tt.affinity, off)
+		}
+		last = kd
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	pocket := square(-20, -20, 40)
+	tests := []struct {
+		name     string
+		ligand   Polygon
+		angle    float64
+		min, max float64
+	}{
+		{"exact fit", pocket, 0, 1, 1},
+		{"turned a quarter", pocket, 90, 0.999, 1},
+		{"turned an eighth", pocket, 45, 0, 0.8},
+		{"too small", square(-10, -10, 20), 0, 0, MinAffinity},
+	}
+	for _, tt := range tests {
+		if got := Affinity(tt.ligand, pocket, tt.angle); got < tt.min || got > tt.max {
+			t.Errorf("%s: Affinity = %g, want %g to %g", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestOccupancy(t *testing.T) {
+	if best := Occupancy(1); best < ActivationThreshold {
+		t.Fatalf("a perfect fit settles at %g, below the threshold of %g", best, ActivationThreshold)
+	}
+	last := 1.0
+	for _, affinity := range []float64{1, 0.9, decoyAffinity, MinAffinity} {
+		occupancy := Occupancy(affinity)
+		if occupancy > last {
+			t.Errorf("Occupancy(%g) = %g rose as the fit got worse", affinity, occupancy)
+		}
+		if affinity < 0.9 && occupancy >= ActivationThreshold {
+			t.Errorf("Occupancy(%g) = %g reaches the threshold of %g", affinity, occupancy, ActivationThreshold)
+		}
+		last = occupancy
+	}
+}
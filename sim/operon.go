@@ -0,0 +1,143 @@
+package sim
+
+// Phases of coupled transcription and translation of a bacterial operon
+const (
+	SigmaBinding = "sigma"        // RNA polymerase waits for the sigma factor that recognises the promoter
+	Transcribing = "transcribing" // RNA polymerase is making the mRNA, which ribosomes can already read
+	Terminated   = "terminated"   // RNA polymerase has left the end of the operon
+	Translated   = "done"         // Every gene of the operon has been translated
+)
+
+// Promoters an operon can have, named after the sigma factor that recognises them
+var Promoters = []string{"housekeeping", "heat shock"}
+
+// Seconds RNA polymerase takes to transcribe, and a ribosome to translate, one segment
+const (
+	segmentTranscribeTime = 1.25
+	segmentTranslateTime  = 1.0
+)
+
+// One segment of a polycistronic operon's mRNA, as laid out along the DNA
+type OperonSegment struct {
+	Kind  string // "SD" (Shine-Dalgarno), "start", "codon" or "stop"
+	Codon string
+	Gene  int
+}
+
+// A ribosome translating one cistron of the nascent mRNA
+type OperonRibosome struct {
+	Gene    int
+	Segment int // Operon segment it is reading
+	Loaded  bool
+	Done    bool
+	Protein []string
+
+	timer float64
+}
+
+// A two-gene operon being transcribed and translated at the same time
+type Operon struct {
+	Segments    []OperonSegment
+	Promoter    string
+	Phase       string
+	Transcribed int // Segments RNA polymerase has already transcribed
+	WrongSigma  bool
+	Ribosomes   [2]OperonRibosome
+
+	rnapTimer float64
+}
+
+// Builds a two-gene operon, each gene with its own Shine-Dalgarno site, start and stop codon
+func (s *Simulation) newOperon() Operon {
+	o := Operon{Phase: SigmaBinding}
+	for gene := 0; gene < 2; gene++ {
+		// Random sense codons must not stop translation early
+		codon := s.RandomRNACodon("AUG")
+		for Translate(codon) == "STOP" {
+			codon = s.RandomRNACodon("AUG")
+		}
+		o.Segments = append(o.Segments,
+			OperonSegment{Kind: "SD", Codon: "AGGAGG", Gene: gene},
+			OperonSegment{Kind: "start", Codon: "AUG", Gene: gene},
+			OperonSegment{Kind: "codon", Codon: codon, Gene: gene},
+			OperonSegment{Kind: "stop", Codon: []string{"UAA", "UAG", "UGA"}[s.rng.Intn(3)], Gene: gene},
+		)
+	}
+	o.Promoter = Promoters[s.rng.Intn(len(Promoters))]
+	for x := range o.Ribosomes {
+		o.Ribosomes[x] = OperonRibosome{Gene: x, Segment: o.FirstSegment(x)}
+	}
+	return o
+}
+
+// Index of the Shine-Dalgarno segment that starts a gene
+func (o *Operon) FirstSegment(gene int) int {
+	for x, seg := range o.Segments {
+		if seg.Gene == gene && seg.Kind == "SD" {
+			return x
+		}
+	}
+	return 0
+}
+
+// Sigma factor recognises the promoter and lets RNA polymerase start transcribing
+func (o *Operon) chooseSigma(promoter string) bool {
+	if o.Phase != SigmaBinding {
+		return false
+	}
+	o.WrongSigma = promoter != o.Promoter
+	if o.WrongSigma {
+		return false
+	}
+	o.Phase = Transcribing
+	return true
+}
+
+// Ribosomes can start on a Shine-Dalgarno site as soon as it has been transcribed,
+// while RNA polymerase is still working further along the same mRNA
+func (o *Operon) loadRibosome(gene int) bool {
+	if gene < 0 || gene >= len(o.Ribosomes) {
+		return false
+	}
+	r := &o.Ribosomes[gene]
+	if r.Loaded || o.Transcribed <= o.FirstSegment(gene) {
+		return false
+	}
+	r.Loaded = true
+	return true
+}
+
+// Advances RNA polymerase and the loaded ribosomes by one fixed step
+func (o *Operon) express() {
+	if o.Phase == Transcribing {
+		o.rnapTimer += TickSeconds
+		if o.rnapTimer >= segmentTranscribeTime {
+			o.rnapTimer = 0
+			o.Transcribed++
+			if o.Transcribed == len(o.Segments) {
+				o.Phase = Terminated
+			}
+		}
+	}
+	for x := range o.Ribosomes {
+		r := &o.Ribosomes[x]
+		if !r.Loaded || r.Done {
+			continue
+		}
+		r.timer += TickSeconds
+		// Ribosomes never overtake RNA polymerase
+		if r.timer >= segmentTranslateTime && r.Segment+1 < o.Transcribed {
+			r.timer = 0
+			r.Segment++
+			seg := o.Segments[r.Segment]
+			if seg.Kind == "stop" {
+				r.Done = true
+			} else {
+				r.Protein = append(r.Protein, Translate(seg.Codon))
+			}
+		}
+	}
+	if o.Phase == Terminated && o.Ribosomes[0].Done && o.Ribosomes[1].Done {
+		o.Phase = Translated
+	}
+}
@@ -0,0 +1,86 @@
+package sim
+
+// Epigenetic marks on the target gene
+type GeneMarks struct {
+	Methylated [5]bool // CpG methylation per template codon; a methylated promoter (codon 0) blocks TF binding
+	Chromatin  string  // "open" (euchromatin) or "closed" (heterochromatin)
+}
+
+// The signal, cell type and gene marks for one run through the pathway.
+// The same signal can produce different outcomes depending on the cell type it reaches.
+type Definition struct {
+	SignalType string
+	CellType   string
+	Marks      GeneMarks
+	Tools      []string // Epigenetic enzymes the cell type expresses: "HAT", "HDAC" and/or "Demethylase"
+	ErrorRate  float64  // Chance RNA polymerase misincorporates a base in each codon, one of ErrorRates
+}
+
+// Polymerase error rates the player can choose between, from a faithful polymerase up
+var ErrorRates = []float64{0, 0.05, 0.25, 0.5}
+
+// Index in ErrorRates of the setting after rate, wrapping back round to a faithful polymerase
+func NextErrorRate(rate float64) int {
+	for x, r := range ErrorRates {
+		if r == rate {
+			return (x + 1) % len(ErrorRates)
+		}
+	}
+	return 0
+}
+
+type CellType struct {
+	Marks GeneMarks
+	Tools []string
+}
+
+var CellTypes = map[string]CellType{
+	// Gene is already accessible and expressed
+	"Liver": {
+		Marks: GeneMarks{Chromatin: "open"},
+		Tools: []string{"HAT", "HDAC"},
+	},
+	// Histones must be acetylated before the gene can be read
+	"Muscle": {
+		Marks: GeneMarks{Chromatin: "closed"},
+		Tools: []string{"HAT", "HDAC"},
+	},
+	// Promoter is methylated and must be demethylated as well
+	"Skin": {
+		Marks: GeneMarks{Methylated: [5]bool{true, false, true, false, false}, Chromatin: "closed"},
+		Tools: []string{"HAT", "HDAC", "Demethylase"},
+	},
+	// Gene is permanently silenced: no demethylase, so the signal has no effect here
+	"Neuron": {
+		Marks: GeneMarks{Methylated: [5]bool{true, true, false, false, true}, Chromatin: "closed"},
+		Tools: []string{"HAT", "HDAC"},
+	},
+}
+
+var CellTypeNames = []string{"Liver", "Muscle", "Skin", "Neuron"}
+
+func (s *Simulation) newDefinition(seed int) Definition {
+	cellType := CellTypeNames[s.rng.Intn(len(CellTypeNames))]
+	return Definition{
+		SignalType: "signal" + string(rune('A'+seed-1)),
+		CellType:   cellType,
+		Marks:      CellTypes[cellType].Marks,
+		Tools:      CellTypes[cellType].Tools,
+	}
+}
+
+// TF and RNA polymerase can only reach a gene with open chromatin and an unmethylated promoter
+func (m GeneMarks) Accessible() bool {
+	return m.Chromatin == "open" && !m.Methylated[0]
+}
+
+// Checks if the cell type can ever make the gene accessible with the tools it has
+func (d Definition) Silenced() bool {
+	if d.Marks.Methylated[0] && !Contains(d.Tools, "Demethylase") {
+		return true
+	}
+	if d.Marks.Chromatin == "closed" && !Contains(d.Tools, "HAT") {
+		return true
+	}
+	return false
+}
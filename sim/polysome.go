@@ -0,0 +1,178 @@
+package sim
+
+// How long a transcript lasts and how fast ribosomes load and read it, in seconds
+const (
+	PolyATailLength   = 200      // Adenines in a fresh poly-A tail
+	deadenylationTime = 0.1      // Seconds to remove one adenine, a half-life of about 10 seconds
+	mirnaDecayTime    = 1.0 / 30 // Seconds per adenine once a microRNA has bound the 3' UTR
+	loadTime          = 4.0      // Seconds between trailing ribosomes loading on the start codon
+	codonTime         = 1.5      // Seconds a trailing ribosome takes to read one codon
+	mirnaArrivalTime  = 5.0      // Seconds before a microRNA turns up near the transcript
+	mirnaHoldTime     = 3.0      // Seconds a bound microRNA holds the 3' UTR before it lets go
+	UTRSite           = "UUGCAC" // Site in the 3' UTR that some microRNAs pair with
+)
+
+// A microRNA near the transcript. Only one whose seed pairs with the 3' UTR site binds it.
+type MicroRNA struct {
+	Seed  string
+	Pairs bool // Seed is SeedFor(UTRSite)
+	Bound bool
+}
+
+// A ribosome that loaded on the transcript behind the lead ribosome and reads it by itself
+type TrailingRibosome struct {
+	Codon    int     // mRNA codon it is reading
+	Progress float64 // Seconds spent reading that codon
+}
+
+// Ribosomes translating one transcript until its poly-A tail is gone and it is degraded
+type Polysome struct {
+	Tail      int
+	Yield     int // Proteins finished by the lead and trailing ribosomes
+	Ribosomes []TrailingRibosome
+	MiRNA     *MicroRNA // nil until a microRNA turns up
+	Parked    bool      // Lead ribosome finished a protein and cannot reinitiate
+	Degraded  bool
+
+	tailTimer  float64
+	loadTimer  float64
+	mirnaTimer float64
+	holdTimer  float64
+}
+
+// Seed that pairs base for base with an RNA site. The two strands run in opposite
+// directions, so the seed is the site's complement read backwards.
+func SeedFor(site string) string {
+	complement := []byte(Transcribe(site))
+	for i, j := 0, len(complement)-1; i < j; i, j = i+1, j-1 {
+		complement[i], complement[j] = complement[j], complement[i]
+	}
+	return string(complement)
+}
+
+func newPolysome() *Polysome {
+	return &Polysome{Tail: PolyATailLength}
+}
+
+// Bound microRNA blocks any new ribosome from initiating, and so does a transcript without a tail
+func (p *Polysome) canInitiate() bool {
+	return p.Tail > 0 && (p.MiRNA == nil || !p.MiRNA.Bound)
+}
+
+// Checks if a trailing ribosome is already on a codon, which blocks the one behind it
+func (p *Polysome) occupied(codon int) bool {
+	for _, r := range p.Ribosomes {
+		if r.Codon == codon {
+			return true
+		}
+	}
+	return false
+}
+
+// Advances the polysome by one fixed step: the tail shortens, trailing ribosomes load and
+// read codons, and a microRNA may turn up
+func (s *Simulation) elongate() {
+	p := s.Polysome
+	if p.Degraded {
+		return
+	}
+
+	p.tailTimer += TickSeconds
+	rate := deadenylationTime
+	if p.MiRNA != nil && p.MiRNA.Bound {
+		rate = mirnaDecayTime
+	}
+	for p.tailTimer >= rate && p.Tail > 0 {
+		p.tailTimer -= rate
+		p.Tail--
+	}
+	if p.Tail <= 0 {
+		// Without a tail the transcript is decapped and degraded, with any ribosomes still on it
+		p.Degraded, p.Ribosomes = true, nil
+		s.Stage, s.Outcome = Complete, Expressed
+		return
+	}
+	if p.MiRNA != nil && p.MiRNA.Bound {
+		p.holdTimer += TickSeconds
+		if p.holdTimer >= mirnaHoldTime {
+			// The microRNA lets go of the 3' UTR, and another may turn up later
+			p.MiRNA, p.mirnaTimer, p.holdTimer = nil, 0, 0
+		}
+	}
+
+	if p.Parked && p.canInitiate() {
+		p.Parked = false
+	}
+	p.loadTimer += TickSeconds
+	if p.canInitiate() && p.loadTimer >= loadTime && !p.occupied(0) {
+		p.loadTimer = 0
+		p.Ribosomes = append(p.Ribosomes, TrailingRibosome{})
+	}
+
+	// Ribosomes are kept in the order they loaded, so each one only waits on the one ahead of it
+	stop := s.StopCodon()
+	remaining := p.Ribosomes[:0]
+	for _, r := range p.Ribosomes {
+		r.Progress = min(r.Progress+TickSeconds, codonTime)
+		if r.Progress < codonTime {
+			remaining = append(remaining, r)
+			continue
+		}
+		switch {
+		case p.MiRNA != nil && p.MiRNA.Bound:
+			// A bound microRNA represses the ribosomes already on the transcript, which
+			// drop off when they finish their codon without making a protein
+		case r.Codon == stop:
+			p.Yield++
+		case len(remaining) > 0 && remaining[len(remaining)-1].Codon == r.Codon+1:
+			// Ribosome ahead has not moved on yet
+			remaining = append(remaining, r)
+		default:
+			r.Codon++
+			r.Progress = 0
+			remaining = append(remaining, r)
+		}
+	}
+	p.Ribosomes = remaining
+
+	p.mirnaTimer += TickSeconds
+	if p.MiRNA == nil && p.mirnaTimer >= mirnaArrivalTime {
+		// Only some microRNAs have a seed complementary to the 3' UTR site
+		p.MiRNA = &MicroRNA{Seed: SeedFor(UTRSite), Pairs: true}
+		if s.rng.Intn(2) == 0 {
+			p.MiRNA = &MicroRNA{Seed: s.RandomRNACodon("") + s.RandomRNACodon("")}
+			p.MiRNA.Pairs = p.MiRNA.Seed == SeedFor(UTRSite)
+		}
+	}
+}
+
+// A microRNA reaching the 3' UTR binds it if its seed pairs with the site, or else floats off
+func (p *Polysome) bindMiRNA() bool {
+	if p.MiRNA == nil || p.MiRNA.Bound {
+		return false
+	}
+	if !p.MiRNA.Pairs {
+		p.clearMiRNA()
+		return false
+	}
+	p.MiRNA.Bound = true
+	return true
+}
+
+// Takes away an unbound microRNA, so another can turn up later
+func (p *Polysome) clearMiRNA() bool {
+	if p.MiRNA == nil || p.MiRNA.Bound {
+		return false
+	}
+	p.MiRNA, p.mirnaTimer = nil, 0
+	return true
+}
+
+// Lead ribosome has let go of a finished protein. It goes back to the start codon if new
+// ribosomes can still initiate, or else waits off the transcript until they can.
+func (s *Simulation) reinitiate() {
+	p := s.Polysome
+	p.Yield++
+	s.Codon, s.CodonComplete = 0, false
+	p.Parked = !p.canInitiate()
+}
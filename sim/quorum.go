@@ -0,0 +1,60 @@
+package sim
+
+// Phases of quorum sensing in a bacterium
+const (
+	Sensing    = "sensing"      // Autoinducers are building up around the sensor kinase
+	Relaying   = "phosphorelay" // Quorum is reached; the sensor can phosphorylate its regulator
+	Responding = "activated"    // Phosphorylated response regulator heads for the operon
+)
+
+// Autoinducer bound to the sensor kinase needed before it autophosphorylates
+const QuorumThreshold = 8
+
+// Seconds between autoinducers the neighbouring bacteria release by themselves, like a
+// low cell density
+const basalSecretionTime = 3.0
+
+// A sensor histidine kinase counting the autoinducers its neighbours release
+type Quorum struct {
+	Phase string
+	Bound int
+
+	secreteTimer float64
+}
+
+// Advances quorum sensing by one fixed step. Returns the neighbour that released an
+// autoinducer by itself this step, or -1.
+func (s *Simulation) sense(neighbours int) int {
+	q := &s.Quorum
+	if q.Phase != Sensing || neighbours <= 0 {
+		return -1
+	}
+	q.secreteTimer += TickSeconds
+	if q.secreteTimer < basalSecretionTime {
+		return -1
+	}
+	q.secreteTimer = 0
+	return s.rng.Intn(neighbours)
+}
+
+// An autoinducer reaching the sensor kinase binds it until quorum is reached
+func (q *Quorum) bind() bool {
+	if q.Phase != Sensing {
+		return false
+	}
+	q.Bound++
+	if q.Bound >= QuorumThreshold {
+		// Sensor histidine kinase autophosphorylates once quorum is reached
+		q.Phase = Relaying
+	}
+	return true
+}
+
+// Phosphate passes from the sensor's histidine to the regulator's aspartate
+func (q *Quorum) relay() bool {
+	if q.Phase != Relaying {
+		return false
+	}
+	q.Phase = Responding
+	return true
+}
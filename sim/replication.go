@@ -0,0 +1,92 @@
+package sim
+
+// Phases of replication at the fork, named after the enzyme that acts in each.
+// Both strands are copied by DNA polymerase, in the "leading" and then the "lagging" phase.
+const (
+	Unwinding  = "helicase"
+	Priming    = "primase"
+	Leading    = "leading"
+	Lagging    = "lagging"
+	Sealing    = "ligase"
+	Replicated = "done"
+)
+
+// A replication fork copying three codons of the gene, on both strands
+type Fork struct {
+	Phase    string
+	Strand   string    // Strand being copied, Leading or Lagging
+	Leading  [3]string // Template codons of the leading strand
+	Lagging  [3]string // Template codons of the lagging strand, the complement of the leading ones
+	Fragment int       // Template codon DNA polymerase is copying
+	Copied   [3]bool   // Codons of the current strand already copied
+	Primers  [3]bool   // RNA primers laid on the current strand
+	Nicks    int       // Gaps left between Okazaki fragments for ligase to seal
+}
+
+// Template codons of the strand currently being copied
+func (f *Fork) Templates() [3]string {
+	if f.Strand == Lagging {
+		return f.Lagging
+	}
+	return f.Leading
+}
+
+// Opens a fork on the sense codons of the gene, between its start and stop codons
+func (s *Simulation) newFork() Fork {
+	f := Fork{Phase: Unwinding, Strand: Leading}
+	for x := 0; x < 3; x++ {
+		f.Leading[x] = s.Template[x+1]
+		f.Lagging[x] = Replicate(f.Leading[x])
+	}
+	return f
+}
+
+// Helicase, primase and ligase each only act in their own phase
+func (s *Simulation) useEnzyme(enzyme string) bool {
+	f := &s.Fork
+	switch {
+	case enzyme == "Helicase" && f.Phase == Unwinding:
+		// Helicase unwinds the double helix, exposing the template bases
+		f.Phase = Priming
+	case enzyme == "Primase" && f.Phase == Priming:
+		// Every new strand, and every Okazaki fragment, starts from an RNA primer
+		f.Primers[f.Fragment] = true
+		f.Phase = f.Strand
+	case enzyme == "Ligase" && f.Phase == Sealing:
+		// Ligase seals the nicks between Okazaki fragments
+		f.Nicks--
+		if f.Nicks == 0 {
+			f.Phase = Replicated
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// DNA polymerase only takes the DNA codon complementary to the template codon it is on
+func (s *Simulation) placeDNACodon(codon string) bool {
+	f := &s.Fork
+	if f.Phase != f.Strand || codon != Replicate(f.Templates()[f.Fragment]) {
+		return false
+	}
+	f.Copied[f.Fragment] = true
+	// Leading strand is synthesized continuously toward the fork (left to right), while the
+	// lagging strand is synthesized away from the fork (right to left) in primed Okazaki fragments
+	if f.Strand == Leading {
+		if f.Fragment < 2 {
+			f.Fragment++
+		} else {
+			f.Strand, f.Fragment = Lagging, 2
+			f.Copied, f.Primers = [3]bool{}, [3]bool{}
+			f.Phase = Priming
+		}
+	} else if f.Fragment > 0 {
+		f.Fragment--
+		f.Phase = Priming
+	} else {
+		f.Nicks = 2
+		f.Phase = Sealing
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package sim
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Generated pockets have 3 to 7 corners this far from their middle, in screen units
+const (
+	minCorners, maxCorners = 3, 7
+	minRadius, maxRadius   = 28, 42
+)
+
+// Largest distance from the middle of a generated shape to a corner
+const MaxRadius = maxRadius
+
+// A receptor on the membrane and the shape of its binding pocket
+type ReceptorSite struct {
+	Name   string
+	Pocket Polygon
+}
+
+type Point struct {
+	X, Y float64
+}
+
+// A convex outline, corners in clockwise order on screen. Binding sites are given around
+// their centre, so they can be turned in place and moved to wherever the molecule is drawn.
+type Polygon []Point
+
+// Returns the outline turned clockwise by an angle in degrees
+func (p Polygon) Rotate(degrees float64) Polygon {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	turned := make(Polygon, len(p))
+	for x, pt := range p {
+		turned[x] = Point{pt.X*cos - pt.Y*sin, pt.X*sin + pt.Y*cos}
+	}
+	return turned
+}
+
+func (p Polygon) Translate(dx, dy float64) Polygon {
+	moved := make(Polygon, len(p))
+	for x, pt := range p {
+		moved[x] = Point{pt.X + dx, pt.Y + dy}
+	}
+	return moved
+}
+
+// Checks if two convex outlines overlap, by looking for an edge of either one that separates them
+func (p Polygon) Overlaps(q Polygon) bool {
+	for _, poly := range []Polygon{p, q} {
+		for x := range poly {
+			a, b := poly[x], poly[(x+1)%len(poly)]
+			axis := Point{a.Y - b.Y, b.X - a.X}
+			pMin, pMax := p.project(axis)
+			qMin, qMax := q.project(axis)
+			if pMax < qMin || qMax < pMin {
+				return false
+			}
+		}
+	}
+	return len(p) > 0 && len(q) > 0
+}
+
+func (p Polygon) project(axis Point) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, pt := range p {
+		d := pt.X*axis.X + pt.Y*axis.Y
+		lo, hi = math.Min(lo, d), math.Max(hi, d)
+	}
+	return lo, hi
+}
+
+// Makes count pockets from a seed, each different enough from the others at every angle
+// that a ligand cut to one of them binds the rest only weakly. The same seed always gives
+// the same pockets.
+func GeneratePockets(seed int64, count int) []Polygon {
+	rng := rand.New(rand.NewSource(seed))
+	var pockets []Polygon
+	for len(pockets) < count {
+		p := randomConvex(rng)
+		if p != nil && distinct(p, pockets) {
+			pockets = append(pockets, p)
+		}
+	}
+	return pockets
+}
+
+// A random outline with corners spread round its middle at uneven angles and distances,
+// or nil if those corners do not make a convex shape
+func randomConvex(rng *rand.Rand) Polygon {
+	corners := minCorners + rng.Intn(maxCorners-minCorners+1)
+	step := 2 * math.Pi / float64(corners)
+	p := make(Polygon, corners)
+	for x := range p {
+		angle := step*float64(x) - math.Pi/2 + (rng.Float64()-0.5)*0.6*step
+		radius := minRadius + rng.Float64()*(maxRadius-minRadius)
+		p[x] = Point{radius * math.Cos(angle), radius * math.Sin(angle)}
+	}
+	for x := range p {
+		a, b, c := p[x], p[(x+1)%corners], p[(x+2)%corners]
+		if (b.X-a.X)*(c.Y-b.Y)-(b.Y-a.Y)*(c.X-b.X) <= 0 {
+			return nil
+		}
+	}
+	return p
+}
+
+func distinct(p Polygon, others []Polygon) bool {
+	for _, q := range others {
+		for angle := 0.0; angle < 360; angle += 10 {
+			if Affinity(p, q, angle) >= decoyAffinity || Affinity(q, p, angle) >= decoyAffinity {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Distance from the middle of a convex outline to its edge in a direction, in radians
+func (p Polygon) radius(direction float64) float64 {
+	sin, cos := math.Sincos(direction)
+	for x := range p {
+		a, b := p[x], p[(x+1)%len(p)]
+		ex, ey := b.X-a.X, b.Y-a.Y
+		den := cos*ey - sin*ex
+		if den == 0 {
+			continue
+		}
+		// Where the ray from the middle crosses this edge, as a distance along the ray and a fraction along the edge
+		t := (a.X*ey - a.Y*ex) / den
+		s := (a.X*sin - a.Y*cos) / den
+		if t > 0 && s >= 0 && s <= 1 {
+			return t
+		}
+	}
+	return 0
+}
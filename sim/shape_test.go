@@ -0,0 +1,62 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func square(x, y, side float64) Polygon {
+	return Polygon{{x, y}, {x + side, y}, {x + side, y + side}, {x, y + side}}
+}
+
+func TestOverlaps(t *testing.T) {
+	triangle := Polygon{{0, 0}, {40, 0}, {0, 40}}
+	tests := []struct {
+		name string
+		p, q Polygon
+		want bool
+	}{
+		{"same square", square(0, 0, 10), square(0, 0, 10), true},
+		{"corner inside", square(0, 0, 10), square(5, 5, 10), true},
+		{"one inside the other", square(0, 0, 30), square(10, 10, 5), true},
+		{"touching edges", square(0, 0, 10), square(10, 0, 10), true},
+		{"apart on x", square(0, 0, 10), square(11, 0, 10), false},
+		{"apart on y", square(0, 0, 10), square(0, 20, 10), false},
+		// Bounding boxes overlap, but the triangle's long edge separates them
+		{"past a diagonal edge", triangle, square(25, 25, 10), false},
+		{"across a diagonal edge", triangle, square(15, 15, 10), true},
+		{"turned and moved", triangle.Rotate(90).Translate(100, 0), square(60, 0, 35), true},
+		{"empty", nil, square(0, 0, 10), false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Overlaps(tt.q); got != tt.want {
+			t.Errorf("%s: Overlaps = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tt.q.Overlaps(tt.p); got != tt.want {
+			t.Errorf("%s, swapped: Overlaps = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGeneratePockets(t *testing.T) {
+	for _, seed := range []int64{1, 2, 42} {
+		a, b := GeneratePockets(seed, 6), GeneratePockets(seed, 6)
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("seed %d gave different pockets", seed)
+		}
+		if len(a) != 6 {
+			t.Fatalf("seed %d gave %d pockets, want 6", seed, len(a))
+		}
+		for x, p := range a {
+			if len(p) < minCorners || len(p) > maxCorners {
+				t.Errorf("seed %d pocket %d has %d corners", seed, x, len(p))
+			}
+			if Affinity(p, p, 0) != 1 {
+				t.Errorf("seed %d pocket %d does not fit itself", seed, x)
+			}
+		}
+	}
+	if reflect.DeepEqual(GeneratePockets(1, 6), GeneratePockets(2, 6)) {
+		t.Fatal("different seeds gave the same pockets")
+	}
+}
@@ -0,0 +1,457 @@
+// Package sim holds the state of one run through the cell signaling pathway and the
+// rules that advance it. It has no rendering or input code, so a run can be stepped
+// without a window; the game draws whatever state the simulation is in.
+package sim
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// Length of one fixed step in seconds. Actions that pass time, such as Dwell or Elongate,
+// each stand for one step, and the game moves everything else in steps of the same length.
+const TickSeconds = 1.0 / 60
+
+// Pathway stages, named after the scenes that show them
+const (
+	Reception     = "Signal Reception"
+	Transduction  = "Signal Transduction"
+	NuclearImport = "Nuclear Import"
+	Replication   = "DNA Replication"
+	Transcription = "Transcription"
+	NuclearExport = "Nuclear Export"
+	Translation   = "Translation"
+	Complete      = "Complete"
+
+	// Prokaryote stages, which replace the ones above
+	QuorumSensing     = "Quorum Sensing"
+	CoupledExpression = "Coupled Expression"
+)
+
+// How a run ended
+const (
+	Expressed = "expressed" // The protein was made
+	Silenced  = "silenced"  // The cell type keeps the gene shut, so the signal had no effect
+)
+
+type Action int
+
+const (
+	None            Action = iota
+	EnterStage             // Target: stage name
+	BindSignal             // Target: receptor type the signal was dropped on, Index: signal's angle in degrees
+	Phosphorylate          // Target: "TK2" or "TFA"
+	ApplyTool              // Target: "HAT", "HDAC" or "Demethylase"
+	SetErrorRate           // Index: entry in ErrorRates, only before transcription starts
+	PlaceCodon             // Target: RNA codon dropped on RNA polymerase
+	Proofread              // Index: transcript base (fragment*3 + base) the player fixed
+	Export                 // Transcript leaves the nucleus as mRNA
+	PlaceTRNA              // Target: tRNA anticodon dropped on the ribosome
+	Advance                // Ribosome has moved on to the next codon
+	Dwell                  // Bound signal spends one fixed step on its receptor, and may fall off
+	UseEnzyme              // Target: "Helicase", "Primase" or "Ligase" at the replication fork
+	PlaceDNACodon          // Target: DNA codon dropped on DNA polymerase
+	BindCarrier            // Target: cargo dropped on importin or exportin
+	EnterPore              // Target: cargo dropped in a nuclear pore with its carrier
+	Translocate            // Target: side of the envelope, Cytoplasm or Nucleus, the complex came out on
+	TogglePolysome         // Switch polysome mode before the first codon is translated
+	Elongate               // Polysome spends one fixed step translating and decaying
+	BindMiRNA              // MicroRNA has reached the 3' UTR
+	ClearMiRNA             // MicroRNA was taken away before it bound
+	Sense                  // Quorum sensing spends one fixed step, Index: neighbouring bacteria that can secrete
+	BindAutoinducer        // Autoinducer reached the sensor kinase
+	Relay                  // Sensor kinase was clicked to phosphorylate the response regulator
+	ChooseSigma            // Target: promoter the chosen sigma factor recognises
+	LoadRibosome           // Index: gene whose Shine-Dalgarno site was clicked
+	Express                // Operon spends one fixed step being transcribed and translated
+	Dimerize               // Target: receptor that paired up with another in the membrane
+)
+
+// One player action, already resolved from mouse or keyboard input by the view
+type Input struct {
+	Action Action
+	Target string
+	Index  int
+}
+
+type Result struct {
+	Accepted bool
+	Mismatch int // Index of a misincorporated base in a placed codon, or -1
+	Picked   int // Index the simulation chose by itself, e.g. the neighbour that secreted, or -1
+}
+
+type Simulation struct {
+	Stage      string
+	Outcome    string // Expressed or Silenced once the run is Complete, "" until then
+	SeedSignal int
+	Pathway    Definition
+	Marks      GeneMarks // Current marks on the gene, changed by epigenetic tools
+	Template   [5]string // DNA template codons, from start to stop
+
+	Ligand         Polygon        // Binding site of the signal, cut to fit one of the receptors
+	Receptors      []ReceptorSite // Receptors on the membrane, 2 to 12 of them
+	BoundReceptor  string
+	Dimers         []string // Receptors paired up in the membrane, which activate TK1 faster when bound
+	Affinity       float64  // How well the signal fits the receptor it is bound to, from 0 to 1
+	Activation     float64  // TK1 activation built up while the signal stays bound; it is released at ActivationThreshold
+	Phosphorylated []string
+
+	Fork      Fork      // Replication of the gene before it is transcribed
+	Transport Transport // Carrier moving cargo through the nuclear envelope
+
+	Fragment   int       // Template codon RNA polymerase is transcribing
+	Transcript [5]string // Codons actually incorporated, errors included
+	Mismatches [5]int    // Index of the misincorporated base in each codon, or -1
+
+	MRNA          [5]string
+	Protein       [5]string
+	Codon         int // mRNA codon the ribosome is reading
+	CodonComplete bool
+	Polysome      *Polysome // Set in polysome mode, where ribosomes keep translating until the mRNA decays
+
+	Quorum Quorum // Bacterial sensor kinase counting autoinducers
+	Operon Operon // Bacterial operon transcribed and translated at the same time
+
+	WrongCodons int // Codons rejected by RNA polymerase since the run started
+
+	Seed   int64
+	source *countingSource
+	rng    *rand.Rand
+}
+
+// Counts every number drawn from the random source, so a replay can check it drew the same ones
+type countingSource struct {
+	rand.Source
+	draws int
+}
+
+func (c *countingSource) Int63() int64 {
+	c.draws++
+	return c.Source.Int63()
+}
+
+func New(seed int64) *Simulation {
+	source := &countingSource{Source: rand.NewSource(seed)}
+	s := &Simulation{
+		Seed:   seed,
+		source: source,
+		rng:    rand.New(source),
+	}
+	s.Reset()
+	return s
+}
+
+// Random source shared by the simulation and anything that must stay in step with it
+func (s *Simulation) Rand() *rand.Rand {
+	return s.rng
+}
+
+// Number of random draws made since the simulation was created
+func (s *Simulation) Draws() int {
+	return s.source.draws
+}
+
+// Starts a new run with a random signal, gene and cell type
+func (s *Simulation) Reset() {
+	s.SeedSignal = s.rng.Intn(4) + 1
+	// The error rate is a setting, so it carries over to the next run
+	rate := s.Pathway.ErrorRate
+	s.Pathway = s.newDefinition(s.SeedSignal)
+	s.Pathway.ErrorRate = rate
+
+	// Every signal's gene starts with TAC (AUG, Met) and ends with a stop codon
+	stops := [4]string{"ACT", "ATT", "ATC", "ATT"}
+	s.Template = [5]string{"TAC", s.RandomDNACodon(), s.RandomDNACodon(), s.RandomDNACodon(), stops[s.SeedSignal-1]}
+	for x := 0; x < 5; x++ {
+		s.MRNA[x] = Transcribe(s.Template[x])
+		s.Protein[x] = Translate(s.MRNA[x])
+	}
+	s.WrongCodons = 0
+
+	// Every run has its own receptors; the signal fits exactly one of them
+	pockets := GeneratePockets(s.rng.Int63(), 2+s.rng.Intn(11))
+	s.Receptors = make([]ReceptorSite, len(pockets))
+	for x, pocket := range pockets {
+		s.Receptors[x] = ReceptorSite{Name: "receptor" + strconv.Itoa(x+1), Pocket: pocket}
+	}
+	s.Ligand = pockets[s.rng.Intn(len(pockets))]
+	s.Stage = Reception
+	s.enter(Reception)
+}
+
+// Resets the progress of a stage, e.g. when the player jumps to it from level selection
+func (s *Simulation) enter(stage string) {
+	s.Stage, s.Outcome = stage, ""
+	switch stage {
+	case Reception:
+		s.BoundReceptor, s.Dimers = "", nil
+		s.Affinity, s.Activation = 0, 0
+	case Transduction:
+		s.Phosphorylated = []string{"TK1"}
+	case NuclearImport:
+		s.Transport = newTransport("import")
+	case NuclearExport:
+		s.Transport = newTransport("export")
+	case Replication:
+		s.Fork = s.newFork()
+	case Transcription:
+		s.Fragment = 0
+		s.Marks = s.Pathway.Marks
+		for x := 0; x < 5; x++ {
+			s.Transcript[x] = Transcribe(s.Template[x])
+			s.Mismatches[x] = -1
+		}
+		// No tool this cell type has can open the gene up, so the run ends here
+		if s.Pathway.Silenced() {
+			s.Stage, s.Outcome = Complete, Silenced
+		}
+	case Translation:
+		s.Codon = 0
+		s.CodonComplete = false
+		if s.Polysome != nil {
+			s.Polysome = newPolysome()
+		}
+	case QuorumSensing:
+		s.Quorum = Quorum{Phase: Sensing}
+	case CoupledExpression:
+		s.Operon = s.newOperon()
+	}
+}
+
+// Advances the simulation by one player action and reports whether the action was allowed
+func (s *Simulation) Step(in Input) Result {
+	result := Result{Mismatch: -1, Picked: -1}
+	switch in.Action {
+	case EnterStage:
+		switch in.Target {
+		case Reception, Transduction, NuclearImport, Replication, Transcription, NuclearExport, Translation,
+			QuorumSensing, CoupledExpression:
+			s.enter(in.Target)
+			result.Accepted = true
+		}
+	case BindSignal:
+		// Any receptor the signal fits well enough at its angle holds it, but only for as long as Dwell allows
+		if s.Stage != Reception || s.BoundReceptor != "" {
+			break
+		}
+		for _, r := range s.Receptors {
+			if affinity := Affinity(s.Ligand, r.Pocket, float64(in.Index)); r.Name == in.Target && affinity >= MinAffinity {
+				s.BoundReceptor, s.Affinity, s.Activation = in.Target, affinity, 0
+				result.Accepted = true
+			}
+		}
+	case Dimerize:
+		if s.Stage != Reception || Contains(s.Dimers, in.Target) {
+			break
+		}
+		for _, r := range s.Receptors {
+			if r.Name == in.Target {
+				s.Dimers = append(s.Dimers, in.Target)
+				result.Accepted = true
+			}
+		}
+	case Dwell:
+		// Accepted while the signal stays bound. TK1 activation rises towards the receptor's
+		// occupancy, and enough of it moves the pathway on; otherwise the signal falls off at
+		// its off rate and the activation is lost.
+		if s.Stage != Reception || s.BoundReceptor == "" {
+			break
+		}
+		rate := ActivationRate(s.Affinity)
+		if Contains(s.Dimers, s.BoundReceptor) {
+			rate *= dimerBoost
+		}
+		s.Activation += rate * (Occupancy(s.Affinity) - s.Activation) * TickSeconds
+		if s.Activation >= ActivationThreshold {
+			s.Stage = Transduction
+			result.Accepted = true
+		} else if s.rng.Float64() < 1-math.Exp(-OffRate(s.Affinity)*TickSeconds) {
+			s.BoundReceptor, s.Affinity, s.Activation = "", 0, 0
+		} else {
+			result.Accepted = true
+		}
+	case Phosphorylate:
+		// Each kinase can only be phosphorylated by the one before it in the cascade
+		prerequisite := map[string]string{"TK2": "TK1", "TFA": "TK2"}[in.Target]
+		if s.Stage == Transduction && prerequisite != "" && Contains(s.Phosphorylated, prerequisite) {
+			if !Contains(s.Phosphorylated, in.Target) {
+				s.Phosphorylated = append(s.Phosphorylated, in.Target)
+			}
+			result.Accepted = true
+		}
+	case ApplyTool:
+		result.Accepted = s.Stage == Transcription && s.applyTool(in.Target)
+	case UseEnzyme:
+		result.Accepted = s.Stage == Replication && s.useEnzyme(in.Target)
+	case PlaceDNACodon:
+		result.Accepted = s.Stage == Replication && s.placeDNACodon(in.Target)
+	case BindCarrier:
+		result.Accepted = s.transporting() && s.Transport.bind(in.Target)
+	case EnterPore:
+		result.Accepted = s.transporting() && s.Transport.enterPore(in.Target)
+	case Translocate:
+		result.Accepted = s.transporting() && s.Transport.translocate(in.Target)
+	case TogglePolysome:
+		// Only before the first protein, as the lead ribosome reinitiates on codon 0 too
+		if s.Stage == Translation && s.Codon == 0 && !s.CodonComplete && (s.Polysome == nil || s.Polysome.Yield == 0) {
+			if s.Polysome == nil {
+				s.Polysome = newPolysome()
+			} else {
+				s.Polysome = nil
+			}
+			result.Accepted = true
+		}
+	case Elongate:
+		if s.Stage == Translation && s.Polysome != nil {
+			s.elongate()
+			result.Accepted = true
+		}
+	case BindMiRNA:
+		result.Accepted = s.Stage == Translation && s.Polysome != nil && s.Polysome.bindMiRNA()
+	case ClearMiRNA:
+		result.Accepted = s.Stage == Translation && s.Polysome != nil && s.Polysome.clearMiRNA()
+	case Sense:
+		if s.Stage == QuorumSensing {
+			result.Picked = s.sense(in.Index)
+			result.Accepted = true
+		}
+	case BindAutoinducer:
+		result.Accepted = s.Stage == QuorumSensing && s.Quorum.bind()
+	case Relay:
+		result.Accepted = s.Stage == QuorumSensing && s.Quorum.relay()
+	case ChooseSigma:
+		result.Accepted = s.Stage == CoupledExpression && s.Operon.chooseSigma(in.Target)
+	case LoadRibosome:
+		result.Accepted = s.Stage == CoupledExpression && s.Operon.loadRibosome(in.Index)
+	case Express:
+		if s.Stage == CoupledExpression {
+			s.Operon.express()
+			result.Accepted = true
+		}
+	case SetErrorRate:
+		if in.Index >= 0 && in.Index < len(ErrorRates) && (s.Stage != Transcription || s.Fragment == 0) {
+			s.Pathway.ErrorRate = ErrorRates[in.Index]
+			result.Accepted = true
+		}
+	case PlaceCodon:
+		if s.Stage != Transcription {
+			break
+		}
+		if s.Fragment < 5 && s.Marks.Accessible() && in.Target == Transcribe(s.Template[s.Fragment]) {
+			codon, index := in.Target, -1
+			if s.ErrorProne() {
+				codon, index = s.misincorporate(codon, s.Pathway.ErrorRate)
+			}
+			s.Transcript[s.Fragment] = codon
+			s.Mismatches[s.Fragment] = index
+			s.Fragment++
+			result.Accepted, result.Mismatch = true, index
+		} else if s.Fragment < 5 {
+			s.WrongCodons++
+		}
+	case Proofread:
+		frag, index := in.Index/3, in.Index%3
+		if s.Stage == Transcription && frag < 5 && s.Mismatches[frag] == index && index != -1 {
+			s.Transcript[frag] = Transcribe(s.Template[frag])
+			s.Mismatches[frag] = -1
+			result.Accepted = true
+		}
+	case Export:
+		// Only a finished transcript leaves the nucleus. Uncorrected errors leave with it
+		// and change the protein.
+		if s.Stage != Transcription || s.Fragment < 5 {
+			break
+		}
+		for x := 0; x < 5; x++ {
+			s.MRNA[x] = s.Transcript[x]
+			s.Protein[x] = Translate(s.MRNA[x])
+		}
+		s.Stage = Translation
+		result.Accepted = true
+	case PlaceTRNA:
+		if s.Stage == Translation && s.Codon < 5 && !s.CodonComplete && Transcribe(in.Target) == s.MRNA[s.Codon] {
+			s.CodonComplete = true
+			result.Accepted = true
+		}
+	case Advance:
+		// Translation ends at the first stop codon, even one a misincorporation put early in the mRNA
+		if s.Stage == Translation && s.CodonComplete {
+			s.Codon++
+			s.CodonComplete = false
+			if s.Codon > s.StopCodon() {
+				if s.Polysome != nil {
+					s.reinitiate()
+				} else {
+					s.Stage, s.Outcome = Complete, Expressed
+				}
+			}
+			result.Accepted = true
+		}
+	}
+	return result
+}
+
+func (s *Simulation) transporting() bool {
+	return s.Stage == NuclearImport || s.Stage == NuclearExport
+}
+
+func (s *Simulation) applyTool(tool string) bool {
+	if !Contains(s.Pathway.Tools, tool) {
+		return false
+	}
+	switch tool {
+	case "HAT":
+		// Acetylated histones loosen their grip on the DNA
+		s.Marks.Chromatin = "open"
+	case "HDAC":
+		// Chromatin can no longer be closed once polymerase is transcribing
+		if s.Fragment > 0 {
+			return false
+		}
+		s.Marks.Chromatin = "closed"
+	case "Demethylase":
+		s.Marks.Methylated = [5]bool{}
+	}
+	return true
+}
+
+// Checks if RNA polymerase misincorporates bases at all
+func (s *Simulation) ErrorProne() bool {
+	return s.Pathway.ErrorRate > 0
+}
+
+// Index of the first stop codon in the mRNA, where the ribosome lets go. A transcript whose
+// stop codon was misincorporated away is read to its last codon.
+func (s *Simulation) StopCodon() int {
+	return firstStop(s.MRNA)
+}
+
+// Index of the first stop codon in the transcript so far, before the end of the gene, or -1.
+// Left uncorrected, this nonsense mutation cuts the protein short.
+func (s *Simulation) PrematureStop() int {
+	if stop := firstStop(s.Transcript); stop < 4 && stop < s.Fragment {
+		return stop
+	}
+	return -1
+}
+
+func firstStop(codons [5]string) int {
+	for x, codon := range codons {
+		if Translate(codon) == "STOP" {
+			return x
+		}
+	}
+	return len(codons) - 1
+}
+
+// Number of misincorporated bases still in the transcript
+func (s *Simulation) MismatchCount() int {
+	count := 0
+	for _, index := range s.Mismatches {
+		if index != -1 {
+			count++
+		}
+	}
+	return count
+}
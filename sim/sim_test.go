@@ -0,0 +1,566 @@
+package sim
+
+import "testing"
+
+// Steps in the given number of seconds of game time
+func steps(seconds float64) int {
+	return int(seconds/TickSeconds) + 1
+}
+
+func accept(t *testing.T, s *Simulation, in Input) Result {
+	t.Helper()
+	res := s.Step(in)
+	if !res.Accepted {
+		t.Fatalf("%+v was refused in stage %q", in, s.Stage)
+	}
+	return res
+}
+
+func refuse(t *testing.T, s *Simulation, in Input) {
+	t.Helper()
+	if s.Step(in).Accepted {
+		t.Fatalf("%+v was accepted in stage %q", in, s.Stage)
+	}
+}
+
+// Gives the run a cell type, so tests do not depend on the one the seed picked
+func withCellType(s *Simulation, name string) {
+	s.Pathway.CellType = name
+	s.Pathway.Marks = CellTypes[name].Marks
+	s.Pathway.Tools = CellTypes[name].Tools
+}
+
+func TestSameSeedSameRun(t *testing.T) {
+	a, b := New(7), New(7)
+	if a.Template != b.Template || a.Pathway.CellType != b.Pathway.CellType || a.Draws() != b.Draws() {
+		t.Fatalf("runs from the same seed differ: %v %v", a.Template, b.Template)
+	}
+}
+
+func TestReception(t *testing.T) {
+	s := New(1)
+	// Signal was cut to fit one receptor exactly; others may hold it, but only briefly
+	fit, misfit, best := "", "", 0.0
+	for _, r := range s.Receptors {
+		affinity := Affinity(s.Ligand, r.Pocket, 0)
+		if affinity > best {
+			fit, best = r.Name, affinity
+		}
+		if affinity < MinAffinity {
+			misfit = r.Name
+		}
+	}
+	if best < 1 {
+		t.Fatalf("signal fits its receptor with affinity %g, want 1", best)
+	}
+	if misfit != "" {
+		refuse(t, s, Input{Action: BindSignal, Target: misfit})
+	}
+
+	// A bound signal may fall off before it activates TK1, and then it is bound again
+	for x := 0; x < steps(60) && s.Stage == Reception; x++ {
+		if s.BoundReceptor == "" {
+			accept(t, s, Input{Action: BindSignal, Target: fit})
+		}
+		s.Step(Input{Action: Dwell})
+	}
+	if s.Stage != Transduction {
+		t.Fatalf("stage is %q after a minute bound, want %q", s.Stage, Transduction)
+	}
+}
+
+// Receptor the signal fits best
+func bestFit(s *Simulation) string {
+	fit, best := "", -1.0
+	for _, r := range s.Receptors {
+		if affinity := Affinity(s.Ligand, r.Pocket, 0); affinity > best {
+			fit, best = r.Name, affinity
+		}
+	}
+	return fit
+}
+
+// Steps a signal spends bound to a receptor before TK1 is activated, binding it again
+// whenever it falls off
+func stepsToActivate(t *testing.T, s *Simulation, receptor string) int {
+	t.Helper()
+	for x := 0; x < steps(60); x++ {
+		if s.BoundReceptor == "" {
+			accept(t, s, Input{Action: BindSignal, Target: receptor})
+		}
+		s.Step(Input{Action: Dwell})
+		if s.Stage == Transduction {
+			return x + 1
+		}
+	}
+	t.Fatalf("TK1 was not activated after a minute bound to %s", receptor)
+	return 0
+}
+
+// Highest TK1 activation a signal held at an angle reaches in a minute, binding it again
+// whenever it falls off
+func peakActivation(t *testing.T, s *Simulation, receptor string, angle int) float64 {
+	t.Helper()
+	peak := 0.0
+	for x := 0; x < steps(60) && s.Stage == Reception; x++ {
+		if s.BoundReceptor == "" {
+			accept(t, s, Input{Action: BindSignal, Target: receptor, Index: angle})
+		}
+		s.Step(Input{Action: Dwell})
+		peak = max(peak, s.Activation)
+	}
+	return peak
+}
+
+func TestWeakFitSettlesBelowStrong(t *testing.T) {
+	strong := New(1)
+	fit := bestFit(strong)
+	peakActivation(t, strong, fit, 0)
+	if strong.Stage != Transduction {
+		t.Fatalf("stage is %q after a minute at the best fit, want %q", strong.Stage, Transduction)
+	}
+
+	weak := New(1)
+	accept(t, weak, Input{Action: Dimerize, Target: fit})
+	peak := peakActivation(t, weak, fit, 4)
+	if weak.Stage != Reception || peak >= ActivationThreshold {
+		t.Fatalf("a weak fit reached %g activation and stage %q, want it to settle below %g", peak, weak.Stage, ActivationThreshold)
+	}
+}
+
+func TestReceptorDimers(t *testing.T) {
+	monomer := New(1)
+	alone := stepsToActivate(t, monomer, bestFit(monomer))
+
+	dimer := New(1)
+	fit := bestFit(dimer)
+	refuse(t, dimer, Input{Action: Dimerize, Target: "no such receptor"})
+	accept(t, dimer, Input{Action: Dimerize, Target: fit})
+	refuse(t, dimer, Input{Action: Dimerize, Target: fit})
+	if paired := stepsToActivate(t, dimer, fit); paired >= alone {
+		t.Fatalf("dimer took %d steps to activate TK1, want fewer than the monomer's %d", paired, alone)
+	}
+	refuse(t, dimer, Input{Action: Dimerize, Target: fit})
+}
+
+func TestTransduction(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: Transduction})
+	refuse(t, s, Input{Action: Phosphorylate, Target: "TFA"})
+	accept(t, s, Input{Action: Phosphorylate, Target: "TK2"})
+	accept(t, s, Input{Action: Phosphorylate, Target: "TFA"})
+}
+
+func TestNuclearImport(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: NuclearImport})
+	refuse(t, s, Input{Action: EnterPore, Target: "TF"})
+	refuse(t, s, Input{Action: BindCarrier, Target: "TK2"})
+	accept(t, s, Input{Action: BindCarrier, Target: "TF"})
+	accept(t, s, Input{Action: EnterPore, Target: "TF"})
+	accept(t, s, Input{Action: Translocate, Target: Nucleus})
+	if !s.Transport.Released || s.Transport.Ran != "GTP" {
+		t.Fatalf("importin in the nucleus: %+v, want cargo released by Ran-GTP", s.Transport)
+	}
+}
+
+func TestNuclearImportBackOut(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: NuclearImport})
+	accept(t, s, Input{Action: BindCarrier, Target: "TF"})
+	accept(t, s, Input{Action: EnterPore, Target: "TF"})
+	accept(t, s, Input{Action: Translocate, Target: Cytoplasm})
+	if s.Transport.Released {
+		t.Fatal("importin let go of its cargo back in the cytoplasm")
+	}
+}
+
+func TestNuclearExport(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: NuclearExport})
+	refuse(t, s, Input{Action: BindCarrier, Target: "TF"})
+	accept(t, s, Input{Action: BindCarrier, Target: "mRNA"})
+	if s.Transport.Ran != "GTP" {
+		t.Fatalf("exportin bound its cargo with Ran %q, want GTP", s.Transport.Ran)
+	}
+	accept(t, s, Input{Action: EnterPore, Target: "mRNA"})
+	accept(t, s, Input{Action: Translocate, Target: Cytoplasm})
+	if !s.Transport.Released || s.Transport.Ran != "GDP" {
+		t.Fatalf("exportin in the cytoplasm: %+v, want cargo released as Ran-GDP", s.Transport)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: Replication})
+	f := &s.Fork
+	refuse(t, s, Input{Action: UseEnzyme, Target: "Primase"})
+	refuse(t, s, Input{Action: PlaceDNACodon, Target: Replicate(f.Leading[0])})
+	accept(t, s, Input{Action: UseEnzyme, Target: "Helicase"})
+
+	// Leading strand is primed once and copied left to right
+	accept(t, s, Input{Action: UseEnzyme, Target: "Primase"})
+	for x := 0; x < 3; x++ {
+		if f.Fragment != x {
+			t.Fatalf("leading strand copying codon %d, want %d", f.Fragment, x)
+		}
+		refuse(t, s, Input{Action: PlaceDNACodon, Target: f.Leading[x]})
+		accept(t, s, Input{Action: PlaceDNACodon, Target: Replicate(f.Leading[x])})
+	}
+
+	// Lagging strand is copied right to left, one primed Okazaki fragment at a time
+	for x := 2; x >= 0; x-- {
+		if f.Strand != Lagging || f.Fragment != x {
+			t.Fatalf("copying %s codon %d, want lagging codon %d", f.Strand, f.Fragment, x)
+		}
+		refuse(t, s, Input{Action: PlaceDNACodon, Target: Replicate(f.Lagging[x])})
+		accept(t, s, Input{Action: UseEnzyme, Target: "Primase"})
+		accept(t, s, Input{Action: PlaceDNACodon, Target: Replicate(f.Lagging[x])})
+	}
+
+	accept(t, s, Input{Action: UseEnzyme, Target: "Ligase"})
+	accept(t, s, Input{Action: UseEnzyme, Target: "Ligase"})
+	if f.Phase != Replicated {
+		t.Fatalf("phase is %q after sealing both nicks, want %q", f.Phase, Replicated)
+	}
+	refuse(t, s, Input{Action: UseEnzyme, Target: "Ligase"})
+}
+
+// Transcribes every codon of the gene, and checks RNA polymerase refuses a wrong one first
+func transcribe(t *testing.T, s *Simulation) {
+	t.Helper()
+	for x := 0; x < 5; x++ {
+		right := Transcribe(s.Template[x])
+		wrong := s.RandomRNACodon(right)
+		wrongs := s.WrongCodons
+		refuse(t, s, Input{Action: PlaceCodon, Target: wrong})
+		if s.WrongCodons != wrongs+1 {
+			t.Fatalf("wrong codon %s was not counted", wrong)
+		}
+		accept(t, s, Input{Action: PlaceCodon, Target: right})
+	}
+}
+
+// Reads every codon up to the first stop codon, and checks the ribosome refuses a wrong tRNA first
+func translate(t *testing.T, s *Simulation) {
+	t.Helper()
+	for x := 0; x <= s.StopCodon(); x++ {
+		right := Transcribe(s.MRNA[x])
+		refuse(t, s, Input{Action: PlaceTRNA, Target: Transcribe(s.RandomRNACodon(s.MRNA[x]))})
+		accept(t, s, Input{Action: PlaceTRNA, Target: right})
+		accept(t, s, Input{Action: Advance})
+	}
+}
+
+func TestTranscriptionAndTranslation(t *testing.T) {
+	s := New(1)
+	withCellType(s, "Liver")
+	accept(t, s, Input{Action: EnterStage, Target: Transcription})
+	transcribe(t, s)
+	accept(t, s, Input{Action: Export})
+	if s.Stage != Translation {
+		t.Fatalf("stage is %q after export, want %q", s.Stage, Translation)
+	}
+	for x := 0; x < 5; x++ {
+		if s.MRNA[x] != Transcribe(s.Template[x]) {
+			t.Fatalf("mRNA codon %d is %s, want %s", x, s.MRNA[x], Transcribe(s.Template[x]))
+		}
+	}
+	translate(t, s)
+	if s.Stage != Complete || s.Outcome != Expressed {
+		t.Fatalf("run ended %q/%q, want %q/%q", s.Stage, s.Outcome, Complete, Expressed)
+	}
+}
+
+// Each action is sent in a stage it does not belong to, with everything else it checks in place
+func TestOutOfStageActions(t *testing.T) {
+	enter := func(s *Simulation, stages ...string) {
+		for _, stage := range stages {
+			accept(t, s, Input{Action: EnterStage, Target: stage})
+		}
+	}
+	tests := []struct {
+		name  string
+		setup func(s *Simulation) Input
+	}{
+		{"phosphorylate in translation", func(s *Simulation) Input {
+			enter(s, Transduction, Translation)
+			return Input{Action: Phosphorylate, Target: "TK2"}
+		}},
+		{"tool in reception", func(s *Simulation) Input {
+			return Input{Action: ApplyTool, Target: "HAT"}
+		}},
+		{"codon in translation", func(s *Simulation) Input {
+			enter(s, Transcription, Translation)
+			return Input{Action: PlaceCodon, Target: Transcribe(s.Template[0])}
+		}},
+		{"proofread in translation", func(s *Simulation) Input {
+			enter(s, Transcription, Translation)
+			s.Mismatches[0] = 1
+			return Input{Action: Proofread, Index: 1}
+		}},
+		{"export from reception", func(s *Simulation) Input {
+			return Input{Action: Export}
+		}},
+		{"export half transcribed", func(s *Simulation) Input {
+			enter(s, Transcription)
+			for x := 0; x < 2; x++ {
+				accept(t, s, Input{Action: PlaceCodon, Target: Transcribe(s.Template[x])})
+			}
+			return Input{Action: Export}
+		}},
+		{"tRNA in transcription", func(s *Simulation) Input {
+			enter(s, Transcription)
+			return Input{Action: PlaceTRNA, Target: Transcribe(s.MRNA[0])}
+		}},
+		{"advance in transcription", func(s *Simulation) Input {
+			enter(s, Transcription)
+			s.CodonComplete = true
+			return Input{Action: Advance}
+		}},
+		{"microRNA in reception", func(s *Simulation) Input {
+			s.Polysome = newPolysome()
+			s.Polysome.MiRNA = &MicroRNA{Pairs: true}
+			return Input{Action: BindMiRNA}
+		}},
+	}
+	for _, tt := range tests {
+		s := New(1)
+		withCellType(s, "Liver")
+		in := tt.setup(s)
+		stage, codon, fragment := s.Stage, s.Codon, s.Fragment
+		if s.Step(in).Accepted {
+			t.Errorf("%s: %+v was accepted in stage %q", tt.name, in, stage)
+		}
+		if s.Stage != stage || s.Codon != codon || s.Fragment != fragment {
+			t.Errorf("%s: refused action moved the run to %q codon %d fragment %d", tt.name, s.Stage, s.Codon, s.Fragment)
+		}
+	}
+}
+
+func TestEpigeneticTools(t *testing.T) {
+	s := New(1)
+	withCellType(s, "Muscle")
+	accept(t, s, Input{Action: EnterStage, Target: Transcription})
+	refuse(t, s, Input{Action: PlaceCodon, Target: Transcribe(s.Template[0])})
+	refuse(t, s, Input{Action: ApplyTool, Target: "Demethylase"})
+	accept(t, s, Input{Action: ApplyTool, Target: "HAT"})
+	accept(t, s, Input{Action: PlaceCodon, Target: Transcribe(s.Template[0])})
+	// Chromatin cannot be shut on a gene that is being transcribed
+	refuse(t, s, Input{Action: ApplyTool, Target: "HDAC"})
+}
+
+func TestSilencedCellType(t *testing.T) {
+	s := New(1)
+	withCellType(s, "Neuron")
+	accept(t, s, Input{Action: EnterStage, Target: Transcription})
+	if s.Stage != Complete || s.Outcome != Silenced {
+		t.Fatalf("neuron run is %q/%q, want %q/%q", s.Stage, s.Outcome, Complete, Silenced)
+	}
+}
+
+func TestErrorRate(t *testing.T) {
+	s := New(3)
+	withCellType(s, "Liver")
+	accept(t, s, Input{Action: EnterStage, Target: Transcription})
+	accept(t, s, Input{Action: SetErrorRate, Index: len(ErrorRates) - 1})
+	if !s.ErrorProne() {
+		t.Fatal("polymerase is faithful after setting the highest error rate")
+	}
+
+	// Half the codons of a long run of genes get a misincorporated base
+	mismatches := 0
+	for run := 0; run < 20; run++ {
+		accept(t, s, Input{Action: EnterStage, Target: Transcription})
+		for x := 0; x < 5; x++ {
+			accept(t, s, Input{Action: PlaceCodon, Target: Transcribe(s.Template[x])})
+		}
+		mismatches += s.MismatchCount()
+	}
+	if mismatches == 0 {
+		t.Fatal("no base was misincorporated at the highest error rate")
+	}
+	refuse(t, s, Input{Action: SetErrorRate, Index: 0})
+
+	for x, index := range s.Mismatches {
+		if index != -1 {
+			refuse(t, s, Input{Action: Proofread, Index: x*3 + (index+1)%3})
+			accept(t, s, Input{Action: Proofread, Index: x*3 + index})
+		}
+	}
+	if s.MismatchCount() != 0 || s.Transcript[1] != Transcribe(s.Template[1]) {
+		t.Fatalf("transcript %v still has errors after proofreading", s.Transcript)
+	}
+}
+
+func TestPrematureStop(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: Translation})
+	s.MRNA[2] = "UAA"
+	if s.StopCodon() != 2 {
+		t.Fatalf("stop codon at %d, want 2", s.StopCodon())
+	}
+	translate(t, s)
+	if s.Stage != Complete || s.Codon != 3 {
+		t.Fatalf("translation ended at codon %d in stage %q, want 3 in %q", s.Codon, s.Stage, Complete)
+	}
+}
+
+func TestPolysome(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: Translation})
+	accept(t, s, Input{Action: TogglePolysome})
+	p := s.Polysome
+
+	// Lead ribosome finishes a protein and goes back to the start codon
+	translate(t, s)
+	if s.Stage != Translation || s.Codon != 0 || p.Yield != 1 {
+		t.Fatalf("after one protein: stage %q, codon %d, yield %d", s.Stage, s.Codon, p.Yield)
+	}
+	refuse(t, s, Input{Action: TogglePolysome})
+
+	trailing := 0
+	for x := 0; x < steps(PolyATailLength*deadenylationTime) && !p.Degraded; x++ {
+		accept(t, s, Input{Action: Elongate})
+		trailing = max(trailing, len(p.Ribosomes))
+		// Only microRNAs that pair with the 3' UTR are left out of this run
+		if p.MiRNA != nil {
+			s.Step(Input{Action: ClearMiRNA})
+		}
+	}
+	if !p.Degraded || s.Stage != Complete || s.Outcome != Expressed {
+		t.Fatalf("tail %d after its half-life; stage %q", p.Tail, s.Stage)
+	}
+	if trailing < 2 || p.Yield < 3 {
+		t.Fatalf("%d trailing ribosomes made %d proteins, want several", trailing, p.Yield)
+	}
+}
+
+func TestMicroRNARepression(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: Translation})
+	accept(t, s, Input{Action: TogglePolysome})
+	p := s.Polysome
+
+	for x := 0; x < steps(mirnaArrivalTime); x++ {
+		accept(t, s, Input{Action: Elongate})
+	}
+	if p.MiRNA == nil {
+		t.Fatalf("no microRNA turned up in %gs", mirnaArrivalTime)
+	}
+
+	// A microRNA whose seed does not pair with the site floats off again
+	p.MiRNA = &MicroRNA{Seed: "AAAAAA"}
+	refuse(t, s, Input{Action: BindMiRNA})
+	if p.MiRNA != nil {
+		t.Fatal("microRNA that does not pair stayed on the transcript")
+	}
+	// The seed pairs with the site read in the other direction
+	if seed := SeedFor(UTRSite); seed != "GUGCAA" {
+		t.Fatalf("seed for %s is %s, want GUGCAA", UTRSite, seed)
+	}
+	p.MiRNA = &MicroRNA{Seed: SeedFor(UTRSite), Pairs: true}
+	accept(t, s, Input{Action: BindMiRNA})
+	refuse(t, s, Input{Action: ClearMiRNA})
+
+	// Bound microRNA stops new ribosomes and speeds up decay until it lets go
+	tail, bound := p.Tail, 0
+	for ; p.MiRNA != nil; bound++ {
+		loaded := len(p.Ribosomes)
+		accept(t, s, Input{Action: Elongate})
+		if p.MiRNA != nil && len(p.Ribosomes) > loaded {
+			t.Fatal("a ribosome loaded on a repressed transcript")
+		}
+		if p.Degraded {
+			t.Fatal("transcript decayed before the microRNA let go")
+		}
+	}
+	if held := float64(bound) * TickSeconds; held > mirnaHoldTime+TickSeconds {
+		t.Fatalf("microRNA held the 3' UTR for %.1fs, want %gs", held, mirnaHoldTime)
+	}
+	if lost := tail - p.Tail; float64(lost)*deadenylationTime < 2*mirnaHoldTime {
+		t.Fatalf("tail lost %d adenines while repressed, want it to decay faster", lost)
+	}
+
+	// Once it has let go, ribosomes load on the transcript again
+	for loaded := len(p.Ribosomes); len(p.Ribosomes) <= loaded; {
+		if p.Degraded {
+			t.Fatal("no ribosome loaded after the microRNA let go")
+		}
+		accept(t, s, Input{Action: Elongate})
+		if p.MiRNA != nil && p.MiRNA.Bound {
+			t.Fatal("another microRNA bound without reaching the site")
+		}
+	}
+}
+
+func TestQuorumSensing(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: QuorumSensing})
+	refuse(t, s, Input{Action: Relay})
+
+	secreted := 0
+	for x := 0; x < steps(basalSecretionTime); x++ {
+		if picked := accept(t, s, Input{Action: Sense, Index: 3}).Picked; picked >= 0 {
+			if picked >= 3 {
+				t.Fatalf("neighbour %d of 3 secreted", picked)
+			}
+			secreted++
+		}
+	}
+	if secreted != 1 {
+		t.Fatalf("%d basal secretions in %gs, want 1", secreted, basalSecretionTime)
+	}
+
+	for x := 0; x < QuorumThreshold; x++ {
+		accept(t, s, Input{Action: BindAutoinducer})
+	}
+	if s.Quorum.Phase != Relaying {
+		t.Fatalf("phase is %q at quorum, want %q", s.Quorum.Phase, Relaying)
+	}
+	refuse(t, s, Input{Action: BindAutoinducer})
+	accept(t, s, Input{Action: Relay})
+	if s.Quorum.Phase != Responding {
+		t.Fatalf("phase is %q after the phosphorelay, want %q", s.Quorum.Phase, Responding)
+	}
+}
+
+func TestCoupledExpression(t *testing.T) {
+	s := New(1)
+	accept(t, s, Input{Action: EnterStage, Target: CoupledExpression})
+	o := &s.Operon
+	for _, promoter := range Promoters {
+		if promoter != o.Promoter {
+			refuse(t, s, Input{Action: ChooseSigma, Target: promoter})
+		}
+	}
+	if !o.WrongSigma {
+		t.Fatal("wrong sigma factor was not reported")
+	}
+	refuse(t, s, Input{Action: LoadRibosome, Index: 0})
+	accept(t, s, Input{Action: ChooseSigma, Target: o.Promoter})
+
+	// Each ribosome loads as soon as its Shine-Dalgarno site is transcribed
+	for x := 0; x < steps(60) && o.Phase != Translated; x++ {
+		accept(t, s, Input{Action: Express})
+		for gene := range o.Ribosomes {
+			if !o.Ribosomes[gene].Loaded && o.Transcribed > o.FirstSegment(gene) {
+				accept(t, s, Input{Action: LoadRibosome, Index: gene})
+			}
+		}
+		for _, r := range o.Ribosomes {
+			if r.Segment >= o.Transcribed && r.Loaded {
+				t.Fatal("ribosome overtook RNA polymerase")
+			}
+		}
+	}
+	if o.Phase != Translated {
+		t.Fatalf("operon is %q after a minute, want %q", o.Phase, Translated)
+	}
+	for gene, r := range o.Ribosomes {
+		codon := o.Segments[o.FirstSegment(gene)+2].Codon
+		if len(r.Protein) != 2 || r.Protein[0] != Translate("AUG") || r.Protein[1] != Translate(codon) {
+			t.Fatalf("gene %d made %v", gene, r.Protein)
+		}
+	}
+}
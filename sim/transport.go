@@ -0,0 +1,84 @@
+package sim
+
+// Sides of the nuclear envelope a carrier complex can be on
+const (
+	Cytoplasm = "cytoplasm"
+	Nucleus   = "nucleus"
+	Pore      = "pore"
+)
+
+// Localization signal each cargo at the nuclear envelope carries, "" for none
+var Cargoes = map[string]string{
+	"TF": "NLS", "TK2": "", "TK1": "",
+	"mRNA": "NES", "RNA Pol": "", "DNA": "",
+}
+
+// Importin or exportin carrying cargo through a nuclear pore. Ran-GTP is high in the
+// nucleus, where RanGEF loads it, and low in the cytoplasm, where RanGAP hydrolyses it
+// to Ran-GDP. Which form of Ran the carrier meets on each side decides when it holds
+// its cargo and when it lets go, and so which way cargo moves.
+type Transport struct {
+	Direction string // "import" or "export"
+	Signal    string // Signal the carrier recognises, "NLS" for importin or "NES" for exportin
+	Cargo     string // Cargo bound to the carrier, or ""
+	Ran       string // Ran bound to the carrier, "GTP", "GDP" or ""
+	Side      string // Where the carrier is, Cytoplasm, Pore or Nucleus
+	Released  bool   // Cargo has been let go on the far side
+}
+
+func newTransport(direction string) Transport {
+	if direction == "import" {
+		return Transport{Direction: direction, Signal: "NLS", Side: Cytoplasm}
+	}
+	return Transport{Direction: direction, Signal: "NES", Side: Nucleus}
+}
+
+// Checks if Ran-GTP is plentiful on a side of the envelope
+func RanGTP(side string) bool {
+	return side == Nucleus
+}
+
+// Importin binds its cargo where Ran-GTP is scarce. Exportin only binds its cargo together
+// with Ran-GTP, so it can only load up in the nucleus.
+func (t *Transport) bind(cargo string) bool {
+	if t.Cargo != "" || t.Released || t.Side == Pore || Cargoes[cargo] != t.Signal {
+		return false
+	}
+	if t.Direction == "import" && RanGTP(t.Side) {
+		return false
+	}
+	if t.Direction == "export" {
+		if !RanGTP(t.Side) {
+			return false
+		}
+		t.Ran = "GTP"
+	}
+	t.Cargo = cargo
+	return true
+}
+
+// Only a loaded carrier is let into a pore
+func (t *Transport) enterPore(cargo string) bool {
+	if t.Cargo == "" || t.Cargo != cargo || t.Side == Pore {
+		return false
+	}
+	t.Side = Pore
+	return true
+}
+
+// The complex comes out of the pore on one side and meets that side's Ran. Importin lets
+// go when Ran-GTP binds it in the nucleus; exportin lets go when RanGAP hydrolyses its
+// Ran-GTP in the cytoplasm. Cargo coming out where it started is not released.
+func (t *Transport) translocate(side string) bool {
+	if t.Side != Pore || (side != Cytoplasm && side != Nucleus) {
+		return false
+	}
+	t.Side = side
+	switch {
+	case t.Direction == "import" && RanGTP(side):
+		t.Ran, t.Released = "GTP", true
+	case t.Direction == "export" && !RanGTP(side):
+		t.Ran, t.Released = "GDP", true
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package main
+
+import "math"
+
+// Broad phase for the physics layer. Molecules are filed by the grid cell their middle is
+// in, with cells as wide as the largest pair of molecules that can touch, so anything
+// touching a molecule is in its cell or one of the eight around it. Molecules are kept on
+// screen, so the grid is a flat slice covering it rather than a map.
+type SpatialHash struct {
+	cell       float64
+	cols, rows int
+	cells      [][]*Particle
+	keys       []int // Occupied cells, in the order they were first filled this step
+}
+
+func newSpatialHash() *SpatialHash {
+	return &SpatialHash{}
+}
+
+func (h *SpatialHash) key(v Vector) int {
+	col := min(max(int(v.x/h.cell), 0), h.cols-1)
+	row := min(max(int(v.y/h.cell), 0), h.rows-1)
+	return row*h.cols + col
+}
+
+// Refiles every molecule where it is now. Cell slices are kept between steps so a
+// scene with thousands of molecules does not allocate every frame.
+func (h *SpatialHash) build(particles []*Particle) {
+	cell := 1.0
+	for _, p := range particles {
+		cell = math.Max(cell, 2*p.radius)
+	}
+	if cell != h.cell {
+		h.cell = cell
+		h.cols = int(math.Ceil(screenWidth/cell)) + 1
+		h.rows = int(math.Ceil(screenHeight/cell)) + 1
+		h.cells = make([][]*Particle, h.cols*h.rows)
+		h.keys = h.keys[:0]
+	}
+	for _, k := range h.keys {
+		h.cells[k] = h.cells[k][:0]
+	}
+	h.keys = h.keys[:0]
+	for _, p := range particles {
+		k := h.key(p.centre())
+		if len(h.cells[k]) == 0 {
+			h.keys = append(h.keys, k)
+		}
+		h.cells[k] = append(h.cells[k], p)
+	}
+}
+
+// Calls fn for every molecule whose middle is within radius of pos
+func (h *SpatialHash) near(pos Vector, radius float64, fn func(p *Particle)) {
+	if h.cols == 0 {
+		return
+	}
+	lo, hi := h.key(newVector(pos.x-radius, pos.y-radius)), h.key(newVector(pos.x+radius, pos.y+radius))
+	for row := lo / h.cols; row <= hi/h.cols; row++ {
+		for col := lo % h.cols; col <= hi%h.cols; col++ {
+			for _, p := range h.cells[row*h.cols+col] {
+				c := p.centre()
+				dx, dy := c.x-pos.x, c.y-pos.y
+				if dx*dx+dy*dy <= radius*radius {
+					fn(p)
+				}
+			}
+		}
+	}
+}
+
+// Cells after a cell that its molecules are paired with, so each pair of neighbouring
+// cells is only visited once
+var forwardCells = [][2]int{{1, 0}, {-1, 1}, {0, 1}, {1, 1}}
+
+// Calls fn once for every pair of molecules that touch
+func (h *SpatialHash) pairs(fn func(a, b *Particle)) {
+	for _, k := range h.keys {
+		cell := h.cells[k]
+		for x, a := range cell {
+			for _, b := range cell[x+1:] {
+				if touches(a, b) {
+					fn(a, b)
+				}
+			}
+		}
+		col, row := k%h.cols, k/h.cols
+		for _, d := range forwardCells {
+			c, r := col+d[0], row+d[1]
+			if c < 0 || c >= h.cols || r >= h.rows {
+				continue
+			}
+			for _, a := range cell {
+				for _, b := range h.cells[r*h.cols+c] {
+					if touches(a, b) {
+						fn(a, b)
+					}
+				}
+			}
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"image/color"
+
+	"github.com/TheLabradorScientist/Cell_Signaling_Pathway_Simulator/m/sim"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 type SceneCreatorFunc func(g *Game)
@@ -14,8 +19,12 @@ type State interface {
 }
 
 type StateMachine struct {
-	state State
-	s_map SceneConstructorMap
+	state   State
+	s_map   SceneConstructorMap
+	pending string      // Scene waiting for its assets before it is built
+	loading *AssetBatch // Assets of the pending scene being decoded
+	current string
+	flight  *Flight // Camera moving through the cell to the pending scene
 }
 
 func newStateMachine(s_map SceneConstructorMap) *StateMachine {
@@ -25,24 +34,95 @@ func newStateMachine(s_map SceneConstructorMap) *StateMachine {
 	}
 }
 
+// Builds the scene at once if its assets are decoded, or else decodes them in the
+// background and shows the loading screen until they are done. Moving between two
+// stages of the cell flies the camera from one to the other first.
 func (s *StateMachine) changeState(g *Game, s_name string) {
+	s.pending = s_name
+	s.loading = nil
+	s.flight = nil
+	from, ok1 := cellRegion(s.current)
+	to, ok2 := cellRegion(s_name)
+	if ok1 && ok2 && s.current != s_name {
+		s.flight = newFlight(from, to)
+	}
+	if !assets.cached(sceneAssets(s_name)) {
+		s.loading = assets.load(sceneAssets(s_name))
+		return
+	}
+	s.enter(g)
+}
+
+func (s *StateMachine) enter(g *Game) {
 	//s.state.volButton.player.Close()
+	s_name := s.pending
+	s.pending, s.loading = "", nil
+	s.current = s_name
+	effects.clear()
+	tweens.clear()
 	s.s_map[s_name](g)
+	pathwaySim.Step(sim.Input{Action: sim.EnterStage, Target: s_name})
 	s.state.Init(g)
-	info = updateInfo()
-	g.switchedScene = true
+	info = updateInfo(s_name)
+	// Decode the next stage while this one is played
+	assets.load(sceneAssets(nextOnPathway(s_name)))
+}
+
+// Enters the pending scene if its assets are done and reports whether a scene is
+// ready to update. Loading never takes up game steps, so recordings replay the same;
+// flights are a fixed number of steps, so they do.
+func (s *StateMachine) ready(g *Game) bool {
+	if s.flight != nil {
+		return true
+	}
+	if s.pending != "" && (s.loading == nil || s.loading.finished()) {
+		s.enter(g)
+	}
+	return s.pending == ""
+}
+
+// Like ready, but blocks until the pending scene's assets are decoded
+func (s *StateMachine) waitReady(g *Game) {
+	if s.pending != "" && s.flight == nil {
+		if s.loading != nil {
+			s.loading.wait()
+		}
+		s.enter(g)
+	}
 }
 
 func (s *StateMachine) update(g *Game) {
+	if s.flight != nil {
+		if s.flight.update() {
+			s.flight = nil
+		}
+		return
+	}
 	s.state.Update(g)
+	effects.update()
+	tweens.update()
 }
 
 func (s *StateMachine) draw(g *Game, screen *ebiten.Image) {
+	if s.flight != nil {
+		s.flight.draw(screen)
+		return
+	}
+	if s.pending != "" {
+		s.drawLoading(screen)
+		return
+	}
 	s.state.Draw(g, screen)
 }
 
-func (s *StateMachine) Scale(g *Game, screen *ebiten.Image) {
-	for _, element := range g.state_array {
-		element.scaleToScreen()
-	} 
-}
\ No newline at end of file
+func (s *StateMachine) drawLoading(screen *ebiten.Image) {
+	progress := 0.0
+	if s.loading != nil {
+		progress = s.loading.progress()
+	}
+	screen.Fill(color.RGBA{25, 0, 90, 255})
+	defaultFont.drawFont(screen, "Loading "+s.pending+"...", 425, 340, color.White)
+	vector.StrokeRect(screen, 425, 370, 400, 30, 3, color.White, false)
+	vector.DrawFilledRect(screen, 425, 370, float32(400*progress), 30, color.RGBA{220, 75, 100, 255}, false)
+	defaultFont.drawFont(screen, fmt.Sprintf("%d%%", int(progress*100)), 600, 440, color.White)
+}